@@ -0,0 +1,537 @@
+package bucket
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MRtecno98/afero/resolver"
+)
+
+const (
+	DefaultBackupFolder = ".bucket/backups"
+
+	// Snapshots are rebuilt here before replacing the live files
+	RestoreStagingFolder = ".bucket/restore"
+	DefaultKeepDaily     = 7
+	DefaultKeepWeekly    = 4
+
+	// Snapshot identifiers are their UTC creation time
+	backupIDFormat = "20060102-150405"
+)
+
+// Root files with these extensions are backed up as configs
+var BackupConfigExts = []string{".yml", ".yaml", ".properties", ".json", ".toml", ".conf", ".txt"}
+
+// Folders in the server root holding configs, other than the plugin ones
+var BackupConfigDirs = []string{"config"}
+
+var ErrBackupNotFound = errors.New("backup not found")
+
+// Backup is a snapshot manifest, listing the chunks of every saved file
+type Backup struct {
+	ID          string    `json:"id"`
+	Created     time.Time `json:"created"`
+	Platform    string    `json:"platform,omitempty"`
+	GameVersion string    `json:"game_version,omitempty"`
+
+	// Folders and files replaced as a whole when restoring
+	Roots []string     `json:"roots"`
+	Files []BackupFile `json:"files"`
+
+	// Compressed size of the chunks added by the snapshot
+	Stored int64 `json:"stored"`
+}
+
+type BackupFile struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Sha256  string    `json:"sha256"`
+	Chunks  []string  `json:"chunks"`
+}
+
+func (b *Backup) TotalSize() int64 {
+	var size int64
+	for _, f := range b.Files {
+		size += f.Size
+	}
+
+	return size
+}
+
+// Worlds are the folders of the level in server.properties and its dimensions
+func (c *OpenContext) Worlds() ([]string, error) {
+	level := "world"
+
+	if file, err := c.Fs.Open("server.properties"); err == nil {
+		props, err := ParseProperties(file)
+		file.Close()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if name := props["level-name"]; name != "" {
+			level = name
+		}
+	}
+
+	var worlds []string
+	for _, w := range []string{level, level + "_nether", level + "_the_end"} {
+		if ok, err := c.Fs.DirExists(w); err != nil {
+			return nil, err
+		} else if ok {
+			worlds = append(worlds, w)
+		}
+	}
+
+	return worlds, nil
+}
+
+// BackupRoots lists the worlds, the plugin config folders and the root configs
+func (c *OpenContext) BackupRoots() ([]string, error) {
+	worlds, err := c.Worlds()
+	if err != nil {
+		return nil, err
+	}
+
+	configs, err := c.ConfigRoots()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(worlds, configs...), nil
+}
+
+// ConfigRoots lists the plugin config folders, the config folders and the root configs
+func (c *OpenContext) ConfigRoots() ([]string, error) {
+	var roots []string
+
+	folder := "plugins"
+	if c.Platform != nil {
+		folder = c.Platform.PluginsFolder()
+	}
+
+	for _, dir := range append([]string{folder}, BackupConfigDirs...) {
+		if ok, err := c.Fs.DirExists(dir); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+
+		if dir != folder {
+			roots = append(roots, dir)
+			continue
+		}
+
+		files, err := c.Fs.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range files {
+			if f.IsDir() {
+				roots = append(roots, path.Join(dir, f.Name()))
+			}
+		}
+	}
+
+	files, err := c.Fs.ReadDir("")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		if !f.IsDir() && slices.Contains(BackupConfigExts, strings.ToLower(path.Ext(f.Name()))) {
+			roots = append(roots, f.Name())
+		}
+	}
+
+	return roots, nil
+}
+
+// BackupStore opens the chunk store of the context snapshots, in the context
+// itself unless the store option points to another context or URL. External
+// stores can be shared by many servers so each one sees only its snapshots
+func (c *OpenContext) BackupStore() (*ChunkStore, error) {
+	conf := c.Config()
+	if conf.Backup.Store == "" {
+		return NewChunkStore(c.Fs, conf.BackupFolder()), nil
+	}
+
+	fs, err := resolver.OpenUrl(conf.FindContext(conf.Backup.Store).URL)
+	if err != nil {
+		return nil, fmt.Errorf("backup store: %w", err)
+	}
+
+	store := NewChunkStore(fs, conf.Backup.Folder)
+	store.Namespace = c.backupNamespace()
+	store.close = fs.Close
+
+	return store, nil
+}
+
+// backupNamespace names the context snapshots in a shared store, after the
+// context or its URL for contexts given on the command line
+func (c *OpenContext) backupNamespace() string {
+	name := c.Name
+	if name == "" || strings.HasPrefix(name, "<") {
+		name = c.URL
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+
+		return '_'
+	}, cmp.Or(name, "default"))
+}
+
+// CreateBackup snapshots the backup roots in the chunk store. Files unchanged
+// since the last snapshot reuse its chunks without being read again
+func (c *OpenContext) CreateBackup(ctx context.Context) (*Backup, error) {
+	roots, err := c.BackupRoots()
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := c.BackupStore()
+	if err != nil {
+		return nil, err
+	}
+
+	defer store.Close()
+
+	// Reused chunks must not be collected before the snapshot is saved
+	unlock, err := store.Lock()
+	if err != nil {
+		return nil, err
+	}
+
+	defer unlock()
+
+	previous := map[string]BackupFile{}
+	if snapshots, err := store.Snapshots(); err != nil {
+		return nil, err
+	} else if len(snapshots) > 0 {
+		for _, f := range snapshots[0].Files {
+			previous[f.Path] = f
+		}
+	}
+
+	now := time.Now().UTC()
+	backup := &Backup{
+		ID:          now.Format(backupIDFormat),
+		Created:     now,
+		Platform:    c.PlatformName(),
+		GameVersion: c.GameVersion(),
+		Roots:       roots,
+	}
+
+	// Snapshots taken in the same second
+	for i := 1; ; i++ {
+		if ok, err := store.HasSnapshot(backup.ID); err != nil {
+			return nil, err
+		} else if !ok {
+			break
+		}
+
+		backup.ID = fmt.Sprintf("%s-%d", now.Format(backupIDFormat), i)
+	}
+
+	for _, root := range roots {
+		if err := c.Fs.Walk(root, func(name string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+				return nil
+			}
+
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			name = filepath.ToSlash(name)
+			if prev, ok := previous[name]; ok && prev.Size == info.Size() && prev.ModTime.Equal(info.ModTime()) {
+				backup.Files = append(backup.Files, prev)
+				return nil
+			}
+
+			file, stored, err := c.chunkFile(store, name, info)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+
+			backup.Files = append(backup.Files, file)
+			backup.Stored += stored
+
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return backup, store.SaveSnapshot(backup)
+}
+
+func (c *OpenContext) chunkFile(store *ChunkStore, name string, info os.FileInfo) (BackupFile, int64, error) {
+	src, err := c.Fs.Open(name)
+	if err != nil {
+		return BackupFile{}, 0, err
+	}
+
+	defer src.Close()
+
+	file := BackupFile{Path: name, ModTime: info.ModTime()}
+
+	var stored int64
+	h := sha256.New()
+	chunker := NewChunker(io.TeeReader(src, h))
+
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return BackupFile{}, 0, err
+		}
+
+		sum, n, err := store.PutChunk(chunk)
+		if err != nil {
+			return BackupFile{}, 0, err
+		}
+
+		file.Chunks = append(file.Chunks, sum)
+		file.Size += int64(len(chunk))
+		stored += n
+	}
+
+	file.Sha256 = hex.EncodeToString(h.Sum(nil))
+	return file, stored, nil
+}
+
+// Backups lists the snapshots of the context, newest first
+func (c *OpenContext) Backups() ([]Backup, error) {
+	store, err := c.BackupStore()
+	if err != nil {
+		return nil, err
+	}
+
+	defer store.Close()
+
+	return store.Snapshots()
+}
+
+func findBackup(store *ChunkStore, id string) (*Backup, error) {
+	backups, err := store.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range backups {
+		if b.ID == id {
+			return &b, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrBackupNotFound, id)
+}
+
+// RestoreBackup replaces the snapshot roots with files rebuilt from the chunks.
+// Files are rebuilt in a staging folder first, so a missing or corrupt chunk
+// or an interruption leaves the live roots untouched
+func (c *OpenContext) RestoreBackup(ctx context.Context, id string) error {
+	store, err := c.BackupStore()
+	if err != nil {
+		return err
+	}
+
+	defer store.Close()
+
+	backup, err := findBackup(store, id)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range backup.Files {
+		if !filepath.IsLocal(f.Path) {
+			return fmt.Errorf("unsafe path in backup: %s", f.Path)
+		}
+
+		for _, sum := range f.Chunks {
+			if ok, err := store.HasChunk(sum); err != nil {
+				return err
+			} else if !ok {
+				return fmt.Errorf("%s: missing chunk %s", f.Path, sum)
+			}
+		}
+	}
+
+	if err := c.Fs.RemoveAll(RestoreStagingFolder); err != nil {
+		return err
+	}
+
+	defer c.Fs.RemoveAll(RestoreStagingFolder)
+
+	for _, f := range backup.Files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := c.rebuildFile(store, f, path.Join(RestoreStagingFolder, f.Path)); err != nil {
+			return fmt.Errorf("%s: %w", f.Path, err)
+		}
+	}
+
+	for _, root := range backup.Roots {
+		if err := c.Fs.RemoveAll(root); err != nil {
+			return err
+		}
+
+		staged := path.Join(RestoreStagingFolder, root)
+		if ok, err := c.Fs.Exists(staged); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+
+		if err := c.Fs.MkdirAll(path.Dir(root), 0755); err != nil {
+			return err
+		}
+
+		if err := c.Fs.Rename(staged, root); err != nil {
+			return fmt.Errorf("%s: %w", root, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *OpenContext) rebuildFile(store *ChunkStore, f BackupFile, target string) error {
+	if err := c.Fs.MkdirAll(path.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	dst, err := c.Fs.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	h := sha256.New()
+	for _, sum := range f.Chunks {
+		chunk, err := store.GetChunk(sum)
+		if err != nil {
+			dst.Close()
+			return err
+		}
+
+		h.Write(chunk)
+		if _, err := dst.Write(chunk); err != nil {
+			dst.Close()
+			return err
+		}
+	}
+
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != f.Sha256 {
+		return errors.New("checksum mismatch after rebuilding")
+	}
+
+	// Unchanged for the next snapshot
+	return c.Fs.Chtimes(target, f.ModTime, f.ModTime)
+}
+
+// PruneBackups deletes the snapshots outside of the retention policy
+// and the chunks only they were using
+func (c *OpenContext) PruneBackups() ([]Backup, error) {
+	store, err := c.BackupStore()
+	if err != nil {
+		return nil, err
+	}
+
+	defer store.Close()
+
+	backups, err := store.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	daily, weekly := c.Config().BackupRetention()
+
+	_, drop := RetainBackups(backups, daily, weekly)
+	for _, b := range drop {
+		if err := store.DeleteSnapshot(b.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := store.Collect(); err != nil {
+		return nil, err
+	}
+
+	return drop, nil
+}
+
+// VerifyBackups checks every chunk in the store, see ChunkStore.Verify
+func (c *OpenContext) VerifyBackups(ctx context.Context) (int, []error, error) {
+	store, err := c.BackupStore()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	defer store.Close()
+
+	return store.Verify(ctx)
+}
+
+// RetainBackups splits the snapshots, sorted newest first, between the ones
+// kept by the retention policy and the ones to delete. The newest snapshot
+// is always kept
+func RetainBackups(backups []Backup, daily, weekly int) (keep []Backup, drop []Backup) {
+	days := map[string]bool{}
+	weeks := map[string]bool{}
+
+	for i, b := range backups {
+		created := b.Created.UTC()
+
+		day := created.Format(time.DateOnly)
+		year, w := created.ISOWeek()
+		week := fmt.Sprintf("%d-%d", year, w)
+
+		kept := i == 0
+		if !days[day] && len(days) < daily {
+			days[day] = true
+			kept = true
+		}
+
+		if !weeks[week] && len(weeks) < weekly {
+			weeks[week] = true
+			kept = true
+		}
+
+		if kept {
+			keep = append(keep, b)
+		} else {
+			drop = append(drop, b)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,208 @@
+package bucket
+
+import (
+	"context"
+	"errors"
+	"path"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/MRtecno98/afero"
+)
+
+func TestBackupRestore(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	files := map[string]string{
+		"server.properties":             "level-name=survival\n",
+		"bukkit.yml":                    "settings: {}\n",
+		"survival/level.dat":            "level",
+		"survival/region/r.0.0.mca":     "region",
+		"survival_nether/level.dat":     "nether",
+		"plugins/Essentials/config.yml": "motd: hi\n",
+	}
+
+	for name, content := range files {
+		fs.MkdirAll(path.Dir(name), 0755)
+		fs.WriteFile(name, []byte(content), 0644)
+	}
+
+	fs.WriteFile("plugins/Essentials.jar", []byte("jar"), 0644)
+	fs.WriteFile("server.jar", []byte("jar"), 0644)
+
+	c := &OpenContext{Fs: fs, LocalConfig: &Config{}}
+
+	backup, err := c.CreateBackup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var saved []string
+	for _, f := range backup.Files {
+		saved = append(saved, f.Path)
+	}
+
+	slices.Sort(saved)
+	if !slices.Equal(saved, []string{"bukkit.yml", "plugins/Essentials/config.yml", "server.properties",
+		"survival/level.dat", "survival/region/r.0.0.mca", "survival_nether/level.dat"}) {
+		t.Fatalf("unexpected backup contents %v", saved)
+	}
+
+	// Changes made after the snapshot
+	fs.WriteFile("survival/region/r.0.0.mca", []byte("griefed"), 0644)
+	fs.WriteFile("survival/region/r.1.0.mca", []byte("new"), 0644)
+	fs.Remove("plugins/Essentials/config.yml")
+
+	backups, err := c.Backups()
+	if err != nil || len(backups) != 1 || backups[0].ID != backup.ID {
+		t.Fatalf("unexpected backups %v: %v", backups, err)
+	}
+
+	if err := c.RestoreBackup(context.Background(), backup.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		if data, err := fs.ReadFile(name); err != nil || string(data) != content {
+			t.Errorf("%s: unexpected content %q after restore: %v", name, data, err)
+		}
+	}
+
+	if ok, _ := fs.Exists("survival/region/r.1.0.mca"); ok {
+		t.Error("world files created after the snapshot survived the restore")
+	}
+
+	if ok, _ := fs.Exists("plugins/Essentials.jar"); !ok {
+		t.Error("plugin jar deleted by the restore")
+	}
+}
+
+func TestRetainBackups(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	// Two snapshots a day for the last 30 days, newest first
+	var backups []Backup
+	for i := 0; i < 60; i++ {
+		created := now.Add(-time.Duration(i) * 12 * time.Hour)
+		backups = append(backups, Backup{ID: created.Format(backupIDFormat), Created: created})
+	}
+
+	keep, drop := RetainBackups(backups, 3, 2)
+	if len(keep)+len(drop) != len(backups) {
+		t.Fatalf("lost backups: %d kept, %d dropped", len(keep), len(drop))
+	}
+
+	var ids []string
+	for _, b := range keep {
+		ids = append(ids, b.ID)
+	}
+
+	// The newest of the last 3 days, which already cover the last 2 weeks
+	expected := []string{"20261019-120000", "20261018-120000", "20261017-120000"}
+	if !slices.Equal(ids, expected) {
+		t.Fatalf("expected %v, kept %v", expected, ids)
+	}
+
+	// The newest of the day and of the weeks starting on the 19th, 12th and 5th
+	keep, _ = RetainBackups(backups, 1, 3)
+	ids = ids[:0]
+	for _, b := range keep {
+		ids = append(ids, b.ID)
+	}
+
+	expected = []string{"20261019-120000", "20261018-120000", "20261011-120000"}
+	if !slices.Equal(ids, expected) {
+		t.Fatalf("expected %v, kept %v", expected, ids)
+	}
+}
+
+func TestRestoreCorruptBackup(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	fs.MkdirAll("world/region", 0755)
+	fs.WriteFile("world/level.dat", []byte("level"), 0644)
+	fs.WriteFile("world/region/r.0.0.mca", []byte("region"), 0644)
+
+	c := &OpenContext{Fs: fs, LocalConfig: &Config{}}
+
+	backup, err := c.CreateBackup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs.WriteFile("world/level.dat", []byte("live"), 0644)
+
+	store, _ := c.BackupStore()
+	for _, f := range backup.Files {
+		if f.Path == "world/region/r.0.0.mca" {
+			store.Fs.WriteFile(store.chunkPath(f.Chunks[0]), []byte("garbage"), 0644)
+		}
+	}
+
+	if err := c.RestoreBackup(context.Background(), backup.ID); !errors.Is(err, ErrCorruptChunk) {
+		t.Fatalf("expected a corrupt chunk error, got %v", err)
+	}
+
+	if data, _ := fs.ReadFile("world/level.dat"); string(data) != "live" {
+		t.Fatal("live world touched by a failed restore")
+	}
+
+	if ok, _ := fs.Exists(RestoreStagingFolder); ok {
+		t.Fatal("staging folder left behind")
+	}
+}
+
+func TestSharedBackupStore(t *testing.T) {
+	shared := t.TempDir()
+
+	open := func(name, level string) *OpenContext {
+		fs := afero.Afero{Fs: afero.NewMemMapFs()}
+		fs.MkdirAll("world", 0755)
+		fs.WriteFile("world/level.dat", []byte(level), 0644)
+
+		return &OpenContext{Context: Context{Name: name}, Fs: fs,
+			LocalConfig: &Config{Backup: BackupConfig{Store: shared, KeepDaily: 1}}}
+	}
+
+	lobby, survival := open("lobby", "lobby"), open("survival", "survival")
+
+	first, err := lobby.CreateBackup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := survival.CreateBackup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each server only sees its own snapshots
+	if backups, err := lobby.Backups(); err != nil || len(backups) != 1 || backups[0].ID != first.ID {
+		t.Fatalf("unexpected lobby backups %v: %v", backups, err)
+	}
+
+	backups, err := survival.Backups()
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("unexpected survival backups %v: %v", backups, err)
+	}
+
+	// Snapshots taken in the same second share the ID across servers
+	if err := survival.RestoreBackup(context.Background(), backups[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := survival.Fs.ReadFile("world/level.dat"); string(data) != "survival" {
+		t.Fatalf("restored another server's snapshot: %q", data)
+	}
+
+	// Pruning one server keeps the chunks of the others
+	if _, err := survival.PruneBackups(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, problems, err := lobby.VerifyBackups(context.Background()); err != nil || len(problems) != 0 {
+		t.Fatalf("lobby snapshot damaged by the survival prune: %v %v", problems, err)
+	}
+
+	if err := lobby.RestoreBackup(context.Background(), first.ID); err != nil {
+		t.Fatal(err)
+	}
+}
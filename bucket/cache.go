@@ -1,11 +1,24 @@
 package bucket
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"reflect"
+	"slices"
+	"time"
 )
 
+const DefaultUnresolvedTTL = 7 * 24 * time.Hour
+
+var ErrNoMatch = errors.New("no match found")
+var ErrUnresolvedCached = errors.New("unresolved (cached)")
+
+// RetryUnresolved ignores the cached failed resolutions
+var RetryUnresolved = false
+
 type PluginDatabase interface {
 	InitializeDatabase(*OpenContext) error
 
@@ -17,6 +30,19 @@ type PluginDatabase interface {
 	CloseDatabase() error
 
 	Plugins() *SymmetricBiMap[string, CachedPlugin]
+
+	GetUnresolved(identifier string) (UnresolvedRecord, bool)
+	SaveUnresolved(record UnresolvedRecord) error
+	DeleteUnresolved(identifier string) error
+}
+
+// UnresolvedRecord marks a local plugin that couldn't be matched to any
+// repository, it stays valid until the jar changes or the record expires
+type UnresolvedRecord struct {
+	LocalIdentifier string    `json:"local_identifier"`
+	File            string    `json:"path"`
+	Hash            string    `json:"hash"`
+	Timestamp       time.Time `json:"timestamp"`
 }
 
 type CachedRecord struct {
@@ -34,6 +60,10 @@ type CachedRecord struct {
 	Website     string   `json:"website,omitempty"`
 
 	Confidence float64 `json:"confidence"`
+
+	// Kept to answer version queries in offline mode, filled
+	// the first time the versions are requested online
+	Versions []string `json:"versions,omitempty"`
 }
 
 type CachedPlugin struct {
@@ -42,6 +72,9 @@ type CachedPlugin struct {
 
 	Repository NamedRepository `json:"-"`
 	requested  bool
+
+	// Where the record is saved again when its versions change
+	database PluginDatabase
 }
 
 func (cr *CachedRecord) UnmarshalJSON(data []byte) error {
@@ -103,23 +136,40 @@ func CachedMatch(local *LocalPlugin, remote RemotePlugin, repo NamedRepository,
 	}
 }
 
+func Unresolved(local *LocalPlugin, hash string) UnresolvedRecord {
+	return UnresolvedRecord{
+		LocalIdentifier: local.GetIdentifier(),
+		File:            local.File.Name(),
+		Hash:            hash,
+		Timestamp:       time.Now(),
+	}
+}
+
+func (ur UnresolvedRecord) Valid(hash string, ttl time.Duration) bool {
+	return ur.Hash == hash && time.Since(ur.Timestamp) < ttl
+}
+
 func NewPluginBiMap() *SymmetricBiMap[string, CachedPlugin] {
 	return NewSymmetricBiMap(func(el CachedPlugin) (string, string) {
 		return el.LocalIdentifier, el.RemoteIdentifier
 	})
 }
 
-func (cp *CachedPlugin) Request() error {
+func (cp *CachedPlugin) Request(ctx context.Context) error {
 	if cp.requested {
 		return nil
 	}
 
+	if err := cp.ForceRequest(ctx); err != nil {
+		return err
+	}
+
 	cp.requested = true
-	return cp.ForceRequest()
+	return nil
 }
 
-func (cp *CachedPlugin) ForceRequest() error {
-	remote, err := cp.Repository.Get(cp.RemoteIdentifier)
+func (cp *CachedPlugin) ForceRequest(ctx context.Context) error {
+	remote, err := cp.Repository.Get(ctx, cp.RemoteIdentifier)
 	if err != nil {
 		return err
 	}
@@ -193,44 +243,72 @@ func (cp *CachedPlugin) GetWebsite() string {
 	return cp.Website
 }
 
-func (cp *CachedPlugin) GetLatestVersion() (RemoteVersion, error) {
-	if err := cp.Request(); err != nil {
+func (cp *CachedPlugin) GetLatestVersion(ctx context.Context) (RemoteVersion, error) {
+	if err := cp.Request(ctx); err != nil {
 		return nil, err
 	}
 
-	return cp.RemotePlugin.GetLatestVersion()
+	return cp.RemotePlugin.GetLatestVersion(ctx)
 }
 
-func (cp *CachedPlugin) GetVersions(limit int) ([]RemoteVersion, error) {
-	if err := cp.Request(); err != nil {
+func (cp *CachedPlugin) GetVersions(ctx context.Context, limit int) ([]RemoteVersion, error) {
+	if err := cp.Request(ctx); err != nil {
 		return nil, err
 	}
 
-	return cp.RemotePlugin.GetVersions(limit)
+	return cp.RemotePlugin.GetVersions(ctx, limit)
 }
 
-func (cp *CachedPlugin) GetVersionByID(version string) (RemoteVersion, error) {
-	if err := cp.Request(); err != nil {
+func (cp *CachedPlugin) GetVersionByID(ctx context.Context, version string) (RemoteVersion, error) {
+	if err := cp.Request(ctx); err != nil {
 		return nil, err
 	}
 
-	return cp.RemotePlugin.GetVersionByID(version)
+	return cp.RemotePlugin.GetVersionByID(ctx, version)
+}
+
+func (cp *CachedPlugin) GetVersionIdentifiers(ctx context.Context) ([]string, error) {
+	if Offline && len(cp.Versions) > 0 {
+		return cp.Versions, nil
+	}
+
+	if err := cp.Request(ctx); err != nil {
+		return nil, err
+	}
+
+	ids, err := cp.RemotePlugin.GetVersionIdentifiers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Requests are served by the HTTP cache until it revalidates,
+	// so the saved list only changes along with the repository
+	if !slices.Equal(ids, cp.Versions) {
+		cp.Versions = ids
+		if cp.database != nil {
+			if err := cp.database.SavePlugin(*cp); err != nil {
+				log.Printf("warn: failed to cache the versions of %s: %v\n", cp.GetName(), err)
+			}
+		}
+	}
+
+	return ids, nil
 }
 
-func (cp *CachedPlugin) GetVersionIdentifiers() ([]string, error) {
-	if err := cp.Request(); err != nil {
+func (cp *CachedPlugin) GetLatestCompatible(ctx context.Context, plt PlatformType) (RemoteVersion, error) {
+	if err := cp.Request(ctx); err != nil {
 		return nil, err
 	}
 
-	return cp.RemotePlugin.GetVersionIdentifiers()
+	return cp.RemotePlugin.GetLatestCompatible(ctx, plt)
 }
 
-func (cp *CachedPlugin) GetLatestCompatible(plt PlatformType) (RemoteVersion, error) {
-	if err := cp.Request(); err != nil {
+func (cp *CachedPlugin) GetLatestCompatibleFor(ctx context.Context, plt PlatformType, game string) (RemoteVersion, error) {
+	if err := cp.Request(ctx); err != nil {
 		return nil, err
 	}
 
-	return cp.RemotePlugin.GetLatestCompatible(plt)
+	return cp.RemotePlugin.GetLatestCompatibleFor(ctx, plt, game)
 }
 
 func (cp *CachedPlugin) GetRepository() Repository {
@@ -0,0 +1,67 @@
+package bucket
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MRtecno98/afero"
+)
+
+func TestUnresolvedCache(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	fs.WriteFile("Custom.jar", []byte("custom"), 0644)
+	jar, _ := fs.Open("Custom.jar")
+
+	db := NewSumfileDatabase()
+	c := &OpenContext{Fs: fs, PluginDatabase: db, LocalConfig: &Config{UnresolvedTTL: "1h"}}
+	if err := db.InitializeDatabase(c); err != nil {
+		t.Fatal(err)
+	}
+
+	local := &LocalPlugin{PluginDescriptor: testDescriptor{"Custom"}, File: jar}
+	if _, err := c.ResolvePlugin(context.Background(), local); !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("expected no match, got %v", err)
+	}
+
+	rec, ok := c.GetUnresolved("custom")
+	if !ok {
+		t.Fatal("failed resolution not cached")
+	}
+
+	if _, err := c.ResolvePlugin(context.Background(), local); !errors.Is(err, ErrUnresolvedCached) {
+		t.Fatalf("expected cached failure, got %v", err)
+	}
+
+	if err := c.ResolvePlugins(context.Background(), []Plugin{local}, nil); err != nil {
+		t.Fatalf("cached failure reported as an error: %v", err)
+	}
+
+	RetryUnresolved = true
+	_, err := c.ResolvePlugin(context.Background(), local)
+	RetryUnresolved = false
+
+	if !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("cached failure not retried: %v", err)
+	}
+
+	// Records older than the configured ttl are tried again
+	rec.Timestamp = time.Now().Add(-2 * time.Hour)
+	if err := c.SaveUnresolved(rec); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.ResolvePlugin(context.Background(), local); !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("expired record still cached: %v", err)
+	}
+
+	if rec, _ = c.GetUnresolved("custom"); time.Since(rec.Timestamp) > time.Minute {
+		t.Fatal("expired record not refreshed")
+	}
+
+	// So are jars changed since the failure
+	if rec.Valid("other", time.Hour) {
+		t.Fatal("record valid for a different jar")
+	}
+}
@@ -0,0 +1,76 @@
+package bucket
+
+import (
+	"bufio"
+	"io"
+)
+
+// Chunk sizes of the content defined chunking, about 1 MB on average.
+// Cut points only depend on the content around them, so an edit in the
+// middle of a file only changes the chunks it touches
+const (
+	ChunkMinSize = 256 << 10
+	ChunkMaxSize = 4 << 20
+	ChunkMask    = 1<<20 - 1
+)
+
+// Random values of the gear rolling hash, fixed so that
+// the same content is always cut in the same chunks
+var gear [256]uint64
+
+func init() {
+	seed := uint64(0x6275636b6574) // splitmix64
+	for i := range gear {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gear[i] = z ^ (z >> 31)
+	}
+}
+
+// Chunker splits a stream in content defined chunks using a gear hash
+type Chunker struct {
+	MinSize int
+	MaxSize int
+	Mask    uint64
+
+	r   *bufio.Reader
+	buf []byte
+}
+
+func NewChunker(r io.Reader) *Chunker {
+	return &Chunker{
+		MinSize: ChunkMinSize,
+		MaxSize: ChunkMaxSize,
+		Mask:    ChunkMask,
+		r:       bufio.NewReaderSize(r, 64<<10),
+	}
+}
+
+// Next returns the next chunk, or io.EOF once the stream is over.
+// The chunk is only valid until the next call
+func (c *Chunker) Next() ([]byte, error) {
+	c.buf = c.buf[:0]
+
+	var hash uint64
+	for {
+		b, err := c.r.ReadByte()
+		if err == io.EOF {
+			if len(c.buf) > 0 {
+				return c.buf, nil
+			}
+
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, err
+		}
+
+		c.buf = append(c.buf, b)
+		hash = hash<<1 + gear[b]
+
+		if len(c.buf) >= c.MaxSize || (len(c.buf) >= c.MinSize && hash&c.Mask == 0) {
+			return c.buf, nil
+		}
+	}
+}
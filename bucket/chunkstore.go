@@ -0,0 +1,422 @@
+package bucket
+
+import (
+	"bytes"
+	"cmp"
+	"compress/zlib"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MRtecno98/afero"
+)
+
+const (
+	chunksFolder    = "chunks"
+	snapshotsFolder = "snapshots"
+	snapshotExt     = ".json"
+
+	locksFolder = "locks"
+	collectLock = "collect.lock"
+	lockExt     = ".lock"
+)
+
+// StaleLockAge is the age after which the locks of interrupted processes are ignored
+var StaleLockAge = 24 * time.Hour
+
+var (
+	ErrCorruptChunk = errors.New("corrupt chunk")
+	ErrStoreBusy    = errors.New("backup store busy")
+)
+
+// ChunkStore keeps deduplicated file chunks addressed by their sha256 and
+// the snapshot manifests listing them, on any afero filesystem
+type ChunkStore struct {
+	Fs   afero.Afero
+	Root string
+
+	// Snapshots are kept apart per namespace, chunks are shared by all
+	Namespace string
+
+	close func()
+}
+
+func NewChunkStore(fs afero.Fs, root string) *ChunkStore {
+	return &ChunkStore{Fs: afero.Afero{Fs: fs}, Root: root}
+}
+
+// Close releases the store filesystem, if it was opened for the store
+func (s *ChunkStore) Close() {
+	if s.close != nil {
+		s.close()
+	}
+}
+
+func (s *ChunkStore) chunkPath(sum string) string {
+	return path.Join(s.Root, chunksFolder, sum[:2], sum)
+}
+
+func (s *ChunkStore) snapshotPath(id string) string {
+	return path.Join(s.Root, snapshotsFolder, s.Namespace, id+snapshotExt)
+}
+
+func (s *ChunkStore) HasChunk(sum string) (bool, error) {
+	return s.Fs.Exists(s.chunkPath(sum))
+}
+
+// PutChunk stores the chunk compressed, unless already present.
+// The stored size is zero for chunks that were already there
+func (s *ChunkStore) PutChunk(data []byte) (sum string, stored int64, err error) {
+	h := sha256.Sum256(data)
+	sum = hex.EncodeToString(h[:])
+
+	if ok, err := s.HasChunk(sum); err != nil || ok {
+		return sum, 0, err
+	}
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return "", 0, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return "", 0, err
+	}
+
+	name := s.chunkPath(sum)
+	if err := s.writeFile(name, buf.Bytes()); err != nil {
+		return "", 0, err
+	}
+
+	return sum, int64(buf.Len()), nil
+}
+
+// GetChunk reads a chunk back, checking it matches its address
+func (s *ChunkStore) GetChunk(sum string) ([]byte, error) {
+	file, err := s.Fs.Open(s.chunkPath(sum))
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	zr, err := zlib.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %v", ErrCorruptChunk, sum, err)
+	}
+
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %v", ErrCorruptChunk, sum, err)
+	}
+
+	if h := sha256.Sum256(data); hex.EncodeToString(h[:]) != sum {
+		return nil, fmt.Errorf("%w %s: checksum mismatch", ErrCorruptChunk, sum)
+	}
+
+	return data, nil
+}
+
+// Chunks lists the addresses of every stored chunk
+func (s *ChunkStore) Chunks() ([]string, error) {
+	root := path.Join(s.Root, chunksFolder)
+	if ok, err := s.Fs.DirExists(root); err != nil || !ok {
+		return nil, err
+	}
+
+	var sums []string
+	err := s.Fs.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() && !strings.HasSuffix(info.Name(), StagingSuffix) {
+			sums = append(sums, info.Name())
+		}
+
+		return nil
+	})
+
+	return sums, err
+}
+
+// writeFile replaces the file through a staging file, so that
+// interrupted writes never leave a partial chunk or manifest
+func (s *ChunkStore) writeFile(name string, data []byte) error {
+	if err := s.Fs.MkdirAll(path.Dir(name), 0755); err != nil {
+		return err
+	}
+
+	part := name + StagingSuffix
+	if err := s.Fs.WriteFile(part, data, 0644); err != nil {
+		s.Fs.Remove(part)
+		return err
+	}
+
+	return renameOver(s.Fs, part, name)
+}
+
+func (s *ChunkStore) SaveSnapshot(b *Backup) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+
+	return s.writeFile(s.snapshotPath(b.ID), data)
+}
+
+func (s *ChunkStore) HasSnapshot(id string) (bool, error) {
+	return s.Fs.Exists(s.snapshotPath(id))
+}
+
+func (s *ChunkStore) DeleteSnapshot(id string) error {
+	return s.Fs.Remove(s.snapshotPath(id))
+}
+
+// Snapshots lists the snapshot manifests of the namespace, newest first
+func (s *ChunkStore) Snapshots() ([]Backup, error) {
+	return s.readSnapshots(path.Join(s.Root, snapshotsFolder, s.Namespace), false)
+}
+
+// readSnapshots reads the manifests in the folder, and in its
+// subfolders with every set to include all namespaces
+func (s *ChunkStore) readSnapshots(root string, every bool) ([]Backup, error) {
+	if ok, err := s.Fs.DirExists(root); err != nil || !ok {
+		return nil, err
+	}
+
+	var snapshots []Backup
+	err := s.Fs.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if filepath.ToSlash(name) != root && !every {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if path.Ext(info.Name()) != snapshotExt {
+			return nil
+		}
+
+		data, err := s.Fs.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		var b Backup
+		if err := json.Unmarshal(data, &b); err != nil {
+			return fmt.Errorf("snapshot %s: %w", info.Name(), err)
+		}
+
+		snapshots = append(snapshots, b)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(snapshots, func(a, b Backup) int {
+		return b.Created.Compare(a.Created)
+	})
+
+	return snapshots, nil
+}
+
+// referenced maps every chunk used by a snapshot of any namespace to one of its users
+func (s *ChunkStore) referenced() (map[string]string, error) {
+	snapshots, err := s.readSnapshots(path.Join(s.Root, snapshotsFolder), true)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]string)
+	for _, b := range snapshots {
+		for _, f := range b.Files {
+			for _, c := range f.Chunks {
+				refs[c] = b.ID + ":" + f.Path
+			}
+		}
+	}
+
+	return refs, nil
+}
+
+// Lock marks a snapshot in progress, its chunks can't be collected until the
+// returned function is called. Snapshots and collections create their lock
+// before checking for the other one, so they never run together
+func (s *ChunkStore) Lock() (unlock func(), err error) {
+	name := path.Join(s.Root, locksFolder,
+		fmt.Sprintf("%s-%x%s", cmp.Or(s.Namespace, "snapshot"), time.Now().UnixNano(), lockExt))
+	if err := s.createLock(name); err != nil {
+		return nil, err
+	}
+
+	locks, err := s.activeLocks()
+	if err == nil && slices.Contains(locks, collectLock) {
+		err = fmt.Errorf("%w: chunks are being collected", ErrStoreBusy)
+	}
+
+	if err != nil {
+		s.Fs.Remove(name)
+		return nil, err
+	}
+
+	return func() { s.Fs.Remove(name) }, nil
+}
+
+// lockCollect prevents snapshots while collecting, chunks deduplicated
+// by a snapshot aren't referenced until its manifest is saved
+func (s *ChunkStore) lockCollect() (unlock func(), err error) {
+	name := path.Join(s.Root, locksFolder, collectLock)
+
+	err = s.createLock(name)
+	if errors.Is(err, os.ErrExist) {
+		// Left by an interrupted collection
+		if info, serr := s.Fs.Stat(name); serr == nil && time.Since(info.ModTime()) >= StaleLockAge {
+			s.Fs.Remove(name)
+			err = s.createLock(name)
+		}
+	}
+
+	if errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("%w: chunks are being collected", ErrStoreBusy)
+	} else if err != nil {
+		return nil, err
+	}
+
+	locks, err := s.activeLocks()
+	if err == nil {
+		if i := slices.IndexFunc(locks, func(l string) bool { return l != collectLock }); i >= 0 {
+			err = fmt.Errorf("%w: snapshot in progress (%s)", ErrStoreBusy, strings.TrimSuffix(locks[i], lockExt))
+		}
+	}
+
+	if err != nil {
+		s.Fs.Remove(name)
+		return nil, err
+	}
+
+	return func() { s.Fs.Remove(name) }, nil
+}
+
+func (s *ChunkStore) createLock(name string) error {
+	if err := s.Fs.MkdirAll(path.Dir(name), 0755); err != nil {
+		return err
+	}
+
+	f, err := s.Fs.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// activeLocks lists the lock files, ignoring the stale ones
+func (s *ChunkStore) activeLocks() ([]string, error) {
+	dir := path.Join(s.Root, locksFolder)
+	if ok, err := s.Fs.DirExists(dir); err != nil || !ok {
+		return nil, err
+	}
+
+	infos, err := s.Fs.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var locks []string
+	for _, info := range infos {
+		if strings.HasSuffix(info.Name(), lockExt) && time.Since(info.ModTime()) < StaleLockAge {
+			locks = append(locks, info.Name())
+		}
+	}
+
+	return locks, nil
+}
+
+// Collect deletes the chunks no snapshot refers to, failing
+// with ErrStoreBusy while a snapshot is in progress
+func (s *ChunkStore) Collect() (removed int, err error) {
+	unlock, err := s.lockCollect()
+	if err != nil {
+		return 0, err
+	}
+
+	defer unlock()
+
+	refs, err := s.referenced()
+	if err != nil {
+		return 0, err
+	}
+
+	sums, err := s.Chunks()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, sum := range sums {
+		if _, ok := refs[sum]; ok {
+			continue
+		}
+
+		if err := s.Fs.Remove(s.chunkPath(sum)); err != nil {
+			return removed, err
+		}
+
+		removed++
+	}
+
+	return removed, nil
+}
+
+// Verify reads every stored chunk and checks every snapshot chunk exists,
+// returning the number of chunks checked and the problems found
+func (s *ChunkStore) Verify(ctx context.Context) (int, []error, error) {
+	refs, err := s.referenced()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	sums, err := s.Chunks()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var problems []error
+	for _, sum := range sums {
+		if err := ctx.Err(); err != nil {
+			return 0, nil, err
+		}
+
+		if _, err := s.GetChunk(sum); err != nil {
+			problems = append(problems, err)
+		}
+
+		delete(refs, sum)
+	}
+
+	missing := slices.Sorted(maps.Keys(refs))
+	for _, sum := range missing {
+		problems = append(problems, fmt.Errorf("missing chunk %s (%s)", sum, refs[sum]))
+	}
+
+	return len(sums), problems, nil
+}
@@ -0,0 +1,177 @@
+package bucket
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/rand"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/MRtecno98/afero"
+)
+
+func chunkSums(t *testing.T, data []byte) map[[32]byte]bool {
+	chunker := NewChunker(bytes.NewReader(data))
+	chunker.MinSize, chunker.MaxSize, chunker.Mask = 1<<10, 16<<10, 1<<12-1
+
+	sums := map[[32]byte]bool{}
+	total := 0
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(chunk) > chunker.MaxSize {
+			t.Fatalf("chunk of %d bytes over the maximum", len(chunk))
+		}
+
+		total += len(chunk)
+		sums[sha256.Sum256(chunk)] = true
+	}
+
+	if total != len(data) {
+		t.Fatalf("chunks cover %d bytes out of %d", total, len(data))
+	}
+
+	return sums
+}
+
+func TestChunkerShift(t *testing.T) {
+	data := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	// Bytes inserted in the middle only change the chunks around them
+	edited := append(append(append([]byte{}, data[:500_000]...), "inserted"...), data[500_000:]...)
+
+	before, after := chunkSums(t, data), chunkSums(t, edited)
+
+	shared := 0
+	for sum := range after {
+		if before[sum] {
+			shared++
+		}
+	}
+
+	if shared < len(before)-3 {
+		t.Fatalf("only %d of %d chunks survived the insertion", shared, len(before))
+	}
+}
+
+func TestIncrementalBackup(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	fs.MkdirAll("world/region", 0755)
+
+	region := make([]byte, 3<<20)
+	rand.New(rand.NewSource(2)).Read(region)
+	fs.WriteFile("world/region/r.0.0.mca", region, 0644)
+	fs.WriteFile("server.properties", []byte("motd=hi\n"), 0644)
+
+	// Store in another context, by URL
+	c := &OpenContext{Fs: fs, LocalConfig: &Config{Backup: BackupConfig{Store: t.TempDir()}}}
+
+	first, err := c.CreateBackup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Stored == 0 {
+		t.Fatal("first snapshot stored nothing")
+	}
+
+	fs.WriteFile("server.properties", []byte("motd=changed\n"), 0644)
+
+	second, err := c.CreateBackup(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if second.Stored == 0 || second.Stored > 1<<10 {
+		t.Fatalf("expected only the changed config to be stored, got %d bytes", second.Stored)
+	}
+
+	checked, problems, err := c.VerifyBackups(context.Background())
+	if err != nil || len(problems) != 0 || checked == 0 {
+		t.Fatalf("verification failed on %d chunks: %v %v", checked, problems, err)
+	}
+
+	store, _ := c.BackupStore()
+	defer store.Close()
+
+	// A corrupted chunk must be reported
+	sum := first.Files[len(first.Files)-1].Chunks[0]
+	for _, f := range first.Files {
+		if f.Path == "world/region/r.0.0.mca" {
+			sum = f.Chunks[0]
+		}
+	}
+
+	store.Fs.WriteFile(store.chunkPath(sum), []byte("garbage"), 0644)
+	if _, problems, _ := c.VerifyBackups(context.Background()); len(problems) != 1 {
+		t.Fatalf("expected one corrupt chunk, got %v", problems)
+	}
+
+	// Unreferenced chunks are collected
+	orphan, _, _ := store.PutChunk([]byte("orphan"))
+	if removed, err := store.Collect(); err != nil || removed != 1 {
+		t.Fatalf("expected the orphan chunk collected, removed %d: %v", removed, err)
+	}
+
+	if ok, _ := store.HasChunk(orphan); ok {
+		t.Fatal("orphan chunk still in the store")
+	}
+
+	if ok, _ := store.Fs.DirExists(path.Join(store.Root, snapshotsFolder)); !ok {
+		t.Fatal("snapshots not stored in the external store")
+	}
+}
+
+// Shared stores are collected by a server while others take snapshots
+func TestChunkStoreLock(t *testing.T) {
+	store := NewChunkStore(afero.NewMemMapFs(), "backups")
+	store.Namespace = "lobby"
+
+	// Deduplicated by a snapshot whose manifest isn't saved yet
+	sum, _, err := store.PutChunk([]byte("chunk"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := store.Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Collect(); !errors.Is(err, ErrStoreBusy) {
+		t.Fatalf("collected during a snapshot: %v", err)
+	}
+
+	if ok, _ := store.HasChunk(sum); !ok {
+		t.Fatal("chunk of the snapshot in progress removed")
+	}
+
+	unlock()
+
+	// Locks of interrupted collections expire
+	store.createLock(path.Join("backups", locksFolder, collectLock))
+	if _, err := store.Lock(); !errors.Is(err, ErrStoreBusy) {
+		t.Fatalf("snapshot started during a collection: %v", err)
+	}
+
+	old := time.Now().Add(-StaleLockAge)
+	store.Fs.Chtimes(path.Join("backups", locksFolder, collectLock), old, old)
+
+	if removed, err := store.Collect(); err != nil || removed != 1 {
+		t.Fatalf("unreferenced chunk not collected: %d %v", removed, err)
+	}
+
+	if _, err := store.Lock(); err != nil {
+		t.Fatalf("collection lock left behind: %v", err)
+	}
+}
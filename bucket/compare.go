@@ -1,41 +1,296 @@
 package bucket
 
 import (
+	"context"
+	"log"
 	"math"
+	"net/url"
 	"strings"
 
 	"github.com/gnames/levenshtein"
 	"golang.org/x/exp/slices"
 )
 
+const DefaultCloseCall float64 = 0.1
+
+// Number of remote versions fetched by the version signal
+const versionSignalLimit = 10
+
 var lvh = levenshtein.NewLevenshtein()
 
-func ComparisonIndex(a, b Plugin) float64 {
-	var index float64 = 1
+// ComparisonSignal is a single piece of evidence used to tell if two
+// plugins are the same one, Compare returns false if the signal can't
+// be evaluated on the given pair (e.g. missing metadata)
+type ComparisonSignal struct {
+	Name   string
+	Weight float64
 
-	if strings.Compare(a.GetName(), b.GetName()) == 0 {
-		return 1
+	// Heavy signals need network requests, so they
+	// are only evaluated on close calls
+	Heavy bool
+
+	// Tiebreakers say nothing about the identity of the plugins, they
+	// only rank the candidates that already clear the threshold
+	Tiebreaker bool
+
+	Compare func(ctx context.Context, a, b Plugin) (float64, bool)
+}
+
+type Comparator struct {
+	Signals   []ComparisonSignal
+	Threshold float64
+	CloseCall float64
+}
+
+var DefaultSignals = []ComparisonSignal{
+	{Name: "name", Weight: 3, Compare: NameSignal},
+	{Name: "authors", Weight: 2, Compare: AuthorsSignal},
+	{Name: "website", Weight: 1, Compare: WebsiteSignal},
+	{Name: "package", Weight: 1, Compare: PackageSignal},
+	{Name: "popularity", Weight: 0.5, Tiebreaker: true, Compare: PopularitySignal},
+	{Name: "version", Weight: 1, Heavy: true, Compare: VersionSignal},
+}
+
+var DefaultComparator = NewComparator(MatchingConfig{})
+
+func NewComparator(conf MatchingConfig) *Comparator {
+	cmp := &Comparator{
+		Signals:   slices.Clone(DefaultSignals),
+		Threshold: conf.Threshold,
+		CloseCall: conf.CloseCall,
+	}
+
+	if cmp.Threshold == 0 {
+		cmp.Threshold = SimilarityTreshold
+	}
+
+	if cmp.CloseCall == 0 {
+		cmp.CloseCall = DefaultCloseCall
+	}
+
+	for name, w := range conf.Weights {
+		i := slices.IndexFunc(cmp.Signals, func(s ComparisonSignal) bool {
+			return s.Name == name
+		})
+
+		if i < 0 {
+			log.Printf("warn: unknown matching signal \"%s\"\n", name)
+			continue
+		}
+
+		cmp.Signals[i].Weight = w
+	}
+
+	return cmp
+}
+
+func ComparisonIndex(ctx context.Context, a, b Plugin) float64 {
+	return DefaultComparator.Index(ctx, a, b)
+}
+
+// Index computes the weighted average of every applicable signal,
+// heavy signals are added only if the result is close to the threshold
+// and tiebreakers only if it's over it
+func (c *Comparator) Index(ctx context.Context, a, b Plugin) float64 {
+	index, weights := c.evaluate(ctx, a, b, false)
+
+	if math.Abs(index-c.Threshold) <= c.CloseCall {
+		index, weights = c.evaluate(ctx, a, b, true)
 	}
 
-	index *= StringSimilarity(a.GetName(), b.GetName())
+	if index < c.Threshold {
+		return index
+	}
 
-	// Too heavy network wise
-	// verA, verB := ExtractVersions(a), ExtractVersions(b)
-	// index *= MatchingComparison(verA, verB)
+	return c.tiebreak(ctx, a, b, index, weights)
+}
 
-	if a, ok := a.(PluginMetadata); ok {
-		if b, ok := b.(PluginMetadata); ok {
-			// Kinda heavy comparison(too much variance)
-			// index *= StringSimilarity(a.GetDescription(), b.GetDescription())
-			// index *= StringSimilarity(a.GetWebsite(), b.GetWebsite())
+func (c *Comparator) evaluate(ctx context.Context, a, b Plugin, heavy bool) (float64, float64) {
+	var sum, weights float64
 
-			index *= MatchingComparison(splitAuthors(a.GetAuthors()), splitAuthors(b.GetAuthors()))
+	for _, s := range c.Signals {
+		if s.Weight <= 0 || s.Tiebreaker || (s.Heavy && !heavy) {
+			continue
+		}
+
+		if v, ok := s.Compare(ctx, a, b); ok {
+			sum += s.Weight * v
+			weights += s.Weight
+		}
+	}
+
+	if weights == 0 {
+		return 0, 0
+	}
+
+	return sum / weights, weights
+}
+
+// tiebreak moves a matching index towards 1 by the share of weight of the
+// tiebreakers, so a match can't fall under the threshold because of them
+func (c *Comparator) tiebreak(ctx context.Context, a, b Plugin, index, weights float64) float64 {
+	for _, s := range c.Signals {
+		if s.Weight <= 0 || !s.Tiebreaker {
+			continue
+		}
+
+		if v, ok := s.Compare(ctx, a, b); ok {
+			index += (1 - index) * v * s.Weight / (weights + s.Weight)
 		}
 	}
 
 	return index
 }
 
+func NameSignal(_ context.Context, a, b Plugin) (float64, bool) {
+	if strings.Compare(a.GetName(), b.GetName()) == 0 {
+		return 1, true
+	}
+
+	return StringSimilarity(a.GetName(), b.GetName()), true
+}
+
+func AuthorsSignal(_ context.Context, a, b Plugin) (float64, bool) {
+	ma, ok := a.(PluginMetadata)
+	if !ok {
+		return 0, false
+	}
+
+	mb, ok := b.(PluginMetadata)
+	if !ok {
+		return 0, false
+	}
+
+	autA, autB := splitAuthors(ma.GetAuthors()), splitAuthors(mb.GetAuthors())
+	if len(autA) == 0 || len(autB) == 0 {
+		return 0, false
+	}
+
+	return MatchingComparison(autA, autB), true
+}
+
+// Code hosting websites, where the first path element
+// identifies the author, not the website itself
+var codeHosts = []string{"github.com", "gitlab.com", "bitbucket.org", "codeberg.org"}
+
+func WebsiteSignal(_ context.Context, a, b Plugin) (float64, bool) {
+	ma, ok := a.(PluginMetadata)
+	if !ok {
+		return 0, false
+	}
+
+	mb, ok := b.(PluginMetadata)
+	if !ok {
+		return 0, false
+	}
+
+	ua, ub := parseWebsite(ma.GetWebsite()), parseWebsite(mb.GetWebsite())
+	if ua == nil || ub == nil {
+		return 0, false
+	}
+
+	if ua.Host != ub.Host {
+		return 0, true
+	}
+
+	if slices.Contains(codeHosts, ua.Host) {
+		if firstPathElement(ua) == firstPathElement(ub) {
+			return 1, true
+		}
+
+		return 0.5, true
+	}
+
+	return 1, true
+}
+
+// PackageSignal checks how much of the main class package
+// can be found in the source code or website URLs
+func PackageSignal(_ context.Context, a, b Plugin) (float64, bool) {
+	da, ok := Unwrap(a).(MainClassDescriptor)
+	if !ok {
+		return 0, false
+	}
+
+	pkg := packageTokens(da.GetMainClass())
+	if len(pkg) == 0 {
+		return 0, false
+	}
+
+	var urls []string
+	if s, ok := Unwrap(b).(SourceLinked); ok {
+		urls = append(urls, s.GetSourceURL())
+	}
+
+	if m, ok := b.(PluginMetadata); ok {
+		urls = append(urls, m.GetWebsite())
+	}
+
+	var tokens []string
+	for _, u := range urls {
+		tokens = append(tokens, urlTokens(u)...)
+	}
+
+	if len(tokens) == 0 {
+		return 0, false
+	}
+
+	found := 0
+	for _, t := range pkg {
+		if slices.Contains(tokens, t) {
+			found++
+		}
+	}
+
+	return float64(found) / float64(len(pkg)), true
+}
+
+// PopularitySignal is a logarithmic score of the remote
+// downloads, one million downloads being the maximum
+func PopularitySignal(_ context.Context, a, b Plugin) (float64, bool) {
+	p, ok := Unwrap(b).(Popular)
+	if !ok {
+		return 0, false
+	}
+
+	return math.Min(1, math.Log10(float64(p.GetDownloads())+1)/6), true
+}
+
+func VersionSignal(ctx context.Context, a, b Plugin) (float64, bool) {
+	va, ok := a.(Versionable)
+	if !ok || va.GetVersion() == "" {
+		return 0, false
+	}
+
+	rb, ok := b.(RemotePlugin)
+	if !ok {
+		return 0, false
+	}
+
+	vers, err := rb.GetVersions(ctx, versionSignalLimit)
+	if err != nil || len(vers) == 0 {
+		return 0, false
+	}
+
+	local := normalizeVersion(va.GetVersion())
+
+	var index float64
+	for _, v := range vers {
+		for _, name := range []string{v.GetVersion(), v.GetVersionName(), v.GetName()} {
+			if name == "" {
+				continue
+			}
+
+			index = math.Max(index, LevenshteinIndex(local, normalizeVersion(name)))
+			if index >= 1 {
+				return 1, true
+			}
+		}
+	}
+
+	return index, true
+}
+
 func MatchingComparison(a, b []string) float64 {
 	var index float64 = 1
 
@@ -84,13 +339,13 @@ func MatchingComparison(a, b []string) float64 {
 	return index
 }
 
-func ExtractVersions(p Plugin) []string {
+func ExtractVersions(ctx context.Context, p Plugin) []string {
 	if p, ok := p.(Versionable); ok {
 		return []string{p.GetVersion()}
 	}
 
 	if p, ok := p.(RemotePlugin); ok {
-		ver, err := GetVersionNames(p)
+		ver, err := GetVersionNames(ctx, p)
 		if err != nil {
 			return []string{}
 		}
@@ -134,6 +389,21 @@ func ShiftSimilarity(a, b string) float64 {
 	return index
 }
 
+// Unwrap returns the underlying plugin implementation, so that
+// optional interfaces can be checked on wrapped plugins too
+func Unwrap(p Plugin) Plugin {
+	switch w := p.(type) {
+	case *LocalPlugin:
+		return w.PluginDescriptor
+	case *CachedPlugin:
+		if w.RemotePlugin != nil {
+			return w.RemotePlugin
+		}
+	}
+
+	return p
+}
+
 // Some people write multiple authors in a single string
 // apparently, and that would be a problem for comparison
 func splitAuthors(authors []string) []string {
@@ -141,9 +411,69 @@ func splitAuthors(authors []string) []string {
 	for _, author := range authors {
 		spl := strings.Split(author, ",")
 		for _, s := range spl {
-			res = append(res, strings.TrimSpace(s))
+			if s = strings.TrimSpace(s); s != "" {
+				res = append(res, s)
+			}
 		}
 	}
 
 	return res
 }
+
+func parseWebsite(website string) *url.URL {
+	website = strings.TrimSpace(website)
+	if website == "" {
+		return nil
+	}
+
+	if !strings.Contains(website, "://") {
+		website = "https://" + website
+	}
+
+	u, err := url.Parse(website)
+	if err != nil || u.Host == "" {
+		return nil
+	}
+
+	u.Host = strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	return u
+}
+
+func firstPathElement(u *url.URL) string {
+	return strings.ToLower(strings.SplitN(strings.Trim(u.Path, "/"), "/", 2)[0])
+}
+
+// Package elements that say nothing about the plugin identity
+var genericPackages = []string{"com", "net", "org", "me", "io", "dev", "de", "it", "fr",
+	"xyz", "github", "gitlab", "plugin", "plugins", "bukkit", "spigot", "paper", "main"}
+
+func packageTokens(class string) []string {
+	spl := strings.Split(strings.ToLower(class), ".")
+	if len(spl) < 2 {
+		return nil
+	}
+
+	var res []string
+	for _, t := range spl[:len(spl)-1] { // Last one is the class name
+		if t != "" && !slices.Contains(genericPackages, t) {
+			res = append(res, t)
+		}
+	}
+
+	return Distinct(res)
+}
+
+func urlTokens(website string) []string {
+	u := parseWebsite(website)
+	if u == nil {
+		return nil
+	}
+
+	return strings.FieldsFunc(strings.ToLower(u.Host+u.Path), func(r rune) bool {
+		return r == '/' || r == '.' || r == '-' || r == '_'
+	})
+}
+
+func normalizeVersion(v string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(v)), "v")
+}
@@ -0,0 +1,98 @@
+package bucket
+
+import (
+	"context"
+	"testing"
+)
+
+// comparedPlugin is a plugin with the metadata read by the signals
+type comparedPlugin struct {
+	name      string
+	authors   []string
+	website   string
+	downloads int
+}
+
+func (p comparedPlugin) GetName() string        { return p.name }
+func (p comparedPlugin) GetIdentifier() string  { return p.name }
+func (p comparedPlugin) GetAuthors() []string   { return p.authors }
+func (p comparedPlugin) GetDescription() string { return "" }
+func (p comparedPlugin) GetWebsite() string     { return p.website }
+func (p comparedPlugin) GetDownloads() int      { return p.downloads }
+
+func TestSignals(t *testing.T) {
+	ctx := context.Background()
+	local := comparedPlugin{name: "Essentials", authors: []string{"drtshock, md_5"},
+		website: "https://github.com/EssentialsX/Essentials"}
+
+	if v, ok := NameSignal(ctx, local, comparedPlugin{name: "Essentials"}); !ok || v != 1 {
+		t.Errorf("same names scored %f", v)
+	}
+
+	if v, _ := NameSignal(ctx, local, comparedPlugin{name: "WorldEdit"}); v >= 0.5 {
+		t.Errorf("different names scored %f", v)
+	}
+
+	if _, ok := AuthorsSignal(ctx, local, comparedPlugin{name: "Essentials"}); ok {
+		t.Error("authors signal applied without remote authors")
+	}
+
+	if v, ok := AuthorsSignal(ctx, local, comparedPlugin{authors: []string{"md_5", "drtshock"}}); !ok || v < 0.9 {
+		t.Errorf("same authors scored %f", v)
+	}
+
+	if v, ok := WebsiteSignal(ctx, local, comparedPlugin{website: "https://github.com/EssentialsX/Chat"}); !ok || v != 1 {
+		t.Errorf("same code host owner scored %f", v)
+	}
+
+	if v, ok := WebsiteSignal(ctx, local, comparedPlugin{website: "https://github.com/someone/Essentials"}); !ok || v != 0.5 {
+		t.Errorf("other code host owner scored %f", v)
+	}
+
+	if v, ok := PopularitySignal(ctx, local, comparedPlugin{downloads: 1_000_000}); !ok || v != 1 {
+		t.Errorf("a million downloads scored %f", v)
+	}
+
+	if _, ok := PopularitySignal(ctx, local, testDescriptor{"Essentials"}); ok {
+		t.Error("popularity applied without a download count")
+	}
+}
+
+func TestComparatorPopularity(t *testing.T) {
+	ctx := context.Background()
+	cmp := NewComparator(MatchingConfig{})
+	local := comparedPlugin{name: "ChestShop"}
+
+	// A popular plugin with a somewhat similar name is still no match
+	popularWrong := cmp.Index(ctx, local, comparedPlugin{name: "QuickShop", downloads: 5_000_000})
+	if popularWrong >= cmp.Threshold {
+		t.Fatalf("popularity pushed a wrong candidate over the threshold: %f", popularWrong)
+	}
+
+	if index := cmp.Index(ctx, local, comparedPlugin{name: "QuickShop"}); index != popularWrong {
+		t.Fatalf("popularity changed a non matching index: %f != %f", index, popularWrong)
+	}
+
+	// Between matching candidates the more popular one ranks first
+	popular := cmp.Index(ctx, local, comparedPlugin{name: "ChestShopX", downloads: 1_000_000})
+	unknown := cmp.Index(ctx, local, comparedPlugin{name: "ChestShopX", downloads: 10})
+	if popular <= unknown || unknown < cmp.Threshold || popular > 1 {
+		t.Fatalf("wrong tiebreak: popular %f, unknown %f", popular, unknown)
+	}
+}
+
+func TestComparatorWeights(t *testing.T) {
+	ctx := context.Background()
+	local := comparedPlugin{name: "LuckPerms", authors: []string{"Luck"}}
+	remote := comparedPlugin{name: "LuckPerms", authors: []string{"someone"}}
+
+	if index := DefaultComparator.Index(ctx, local, remote); index >= 1 {
+		t.Fatalf("different authors didn't lower the index: %f", index)
+	}
+
+	// Disabled signals are left out of the average
+	names := NewComparator(MatchingConfig{Weights: map[string]float64{"authors": 0, "popularity": 0}})
+	if index := names.Index(ctx, local, remote); index != 1 {
+		t.Fatalf("expected only the name signal, got %f", index)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"time"
 
 	"github.com/MRtecno98/afero"
 	"gopkg.in/yaml.v2"
@@ -18,18 +19,89 @@ const ConfigName string = "bucketrc.yml"
 var GlobalConfig *Config = &Config{}
 
 type Config struct {
-	ActiveContexts []string           `yaml:"active-contexts"`
-	Contexts       []Context          `yaml:"contexts"`
-	Platform       string             `yaml:"platform"`
-	Multithread    bool               `yaml:"multithread"`
+	ActiveContexts []string           `yaml:"active-contexts,omitempty"`
+	Contexts       []Context          `yaml:"contexts,omitempty"`
+	Platform       string             `yaml:"platform,omitempty"`
+	Multithread    bool               `yaml:"multithread,omitempty"`
 	SumDB          string             `yaml:"sumdb,omitempty"`
-	Repositories   []RepositoryConfig `yaml:"repositories"`
+	Repositories   []RepositoryConfig `yaml:"repositories,omitempty"`
+	Matching       MatchingConfig     `yaml:"matching,omitempty"`
+	UnresolvedTTL  string             `yaml:"unresolved-ttl,omitempty"`
+	Workers        int                `yaml:"workers,omitempty"`
+	CacheSize      int                `yaml:"cache-size,omitempty"`
+
+	// Overrides the detected Minecraft version of the server
+	MinecraftVersion string `yaml:"minecraft-version,omitempty"`
+
+	Plugins map[string]PluginConfig `yaml:"plugins,omitempty"`
+
+	Backup BackupConfig `yaml:"backup,omitempty"`
+
+	Overlays  []OverlayConfig   `yaml:"overlays,omitempty"`
+	Variables map[string]string `yaml:"variables,omitempty"`
+
+	// Providers of the ${secret:NAME} references, tried in order
+	Secrets []SecretConfig `yaml:"secrets,omitempty"`
+
+	// Settings of the server config files, by file and key
+	Server map[string]yaml.MapSlice `yaml:"server,omitempty"`
+}
+
+type SecretConfig struct {
+	Provider string            `yaml:"provider"`
+	Options  map[string]string `yaml:"options,omitempty"`
+}
+
+// BackupConfig sets where snapshots are stored and how many survive a prune,
+// the newest snapshot of each of the last KeepDaily days and KeepWeekly weeks
+type BackupConfig struct {
+	// Context name or URL of the chunk store, the context itself if unset
+	Store string `yaml:"store,omitempty"`
+
+	// Store folder, the root of external stores if unset
+	Folder     string `yaml:"folder,omitempty"`
+	KeepDaily  int    `yaml:"keep-daily,omitempty"`
+	KeepWeekly int    `yaml:"keep-weekly,omitempty"`
+}
+
+// OverlayConfig renders the files of a shared base folder in the target
+// folder, deep merging the patches in the YAML files with the same path
+type OverlayConfig struct {
+	// Context name or URL of the base configs
+	Base string `yaml:"base,omitempty"`
+
+	// Folder of the base configs, the root of the base if unset
+	Folder string `yaml:"folder,omitempty"`
+	Target string `yaml:"target"`
+
+	Patches map[string]yaml.MapSlice `yaml:"patches,omitempty"`
+}
+
+// PluginConfig holds the settings of a single plugin, by name or identifier
+type PluginConfig struct {
+	// Repository tried first when resolving the plugin
+	Repository string `yaml:"repository,omitempty"`
+}
+
+type MatchingConfig struct {
+	Threshold float64            `yaml:"threshold,omitempty"`
+	CloseCall float64            `yaml:"close-call,omitempty"`
+	Weights   map[string]float64 `yaml:"weights,omitempty"`
 }
 
 type RepositoryConfig struct {
 	Name     string            `yaml:"name"`
 	Provider string            `yaml:"provider"`
 	Options  map[string]string `yaml:"options"`
+
+	// Maximum number of concurrent resolutions on this repository
+	Concurrency int `yaml:"concurrency,omitempty"`
+
+	// Matches from higher priority repositories are preferred
+	Priority int `yaml:"priority,omitempty"`
+
+	// Base URL replacing the upstream API and download hosts
+	Mirror string `yaml:"mirror,omitempty"`
 }
 
 func (rc *RepositoryConfig) GetName() string {
@@ -40,16 +112,42 @@ func (rc *RepositoryConfig) GetName() string {
 	}
 }
 
+// ProviderOptions are the options passed to the repository constructor,
+// including the ones set through dedicated fields
+func (rc *RepositoryConfig) ProviderOptions() map[string]string {
+	opts := make(map[string]string, len(rc.Options)+1)
+	for k, v := range rc.Options {
+		opts[k] = v
+	}
+
+	if rc.Mirror != "" {
+		opts["mirror"] = rc.Mirror
+	}
+
+	return opts
+}
+
 func (rc *RepositoryConfig) MakeRepository(oc *OpenContext) (*NamedRepository, error) {
 	if constr, ok := Repositories[rc.Provider]; ok {
+		limit := rc.Concurrency
+		if limit <= 0 {
+			limit = DefaultRepositoryConcurrency
+		}
+
+		repo, err := constr(oc.Lock, oc, rc.ProviderOptions())
+		if err != nil {
+			return nil, fmt.Errorf("repository %s: %w", rc.GetName(), err)
+		}
+
 		return &NamedRepository{RepositoryConfig: *rc,
-			Repository: constr(context.TODO(), oc, rc.Options)}, nil
+			Repository: repo,
+			limiter:    make(chan struct{}, limit)}, nil
 	} else {
 		return nil, fmt.Errorf("unknown repository: %s", rc.Name)
 	}
 }
 
-func (c *Config) MakeWorkspace() (*Workspace, error) {
+func (c *Config) MakeWorkspace(lock context.Context) (*Workspace, error) {
 	active := make([]Context, len(c.ActiveContexts))
 
 	for i, v := range c.ActiveContexts {
@@ -60,7 +158,60 @@ func (c *Config) MakeWorkspace() (*Workspace, error) {
 		}
 	}
 
-	return CreateWorkspace(active...)
+	return CreateWorkspace(lock, active...)
+}
+
+func (c *Config) UnresolvedExpiration() time.Duration {
+	if c.UnresolvedTTL == "" {
+		return DefaultUnresolvedTTL
+	}
+
+	ttl, err := time.ParseDuration(c.UnresolvedTTL)
+	if err != nil {
+		log.Printf("warn: invalid unresolved-ttl \"%s\": %v\n", c.UnresolvedTTL, err)
+		return DefaultUnresolvedTTL
+	}
+
+	return ttl
+}
+
+func (c *Config) ResolutionWorkers() int {
+	if !c.Multithread {
+		return 1
+	}
+
+	if c.Workers <= 0 {
+		return DefaultWorkers
+	}
+
+	return c.Workers
+}
+
+func (c *Config) BackupFolder() string {
+	if c.Backup.Folder == "" {
+		return DefaultBackupFolder
+	}
+
+	return c.Backup.Folder
+}
+
+// BackupRetention is the number of daily and weekly snapshots kept
+func (c *Config) BackupRetention() (daily int, weekly int) {
+	if c.Backup.KeepDaily <= 0 && c.Backup.KeepWeekly <= 0 {
+		return DefaultKeepDaily, DefaultKeepWeekly
+	}
+
+	return c.Backup.KeepDaily, c.Backup.KeepWeekly
+}
+
+// PluginSettings looks up the plugin configuration by name, then by identifier
+func (c *Config) PluginSettings(plugin Plugin) (PluginConfig, bool) {
+	if pc, ok := c.Plugins[plugin.GetName()]; ok {
+		return pc, true
+	}
+
+	pc, ok := c.Plugins[plugin.GetIdentifier()]
+	return pc, ok
 }
 
 func (c *Config) ContextNames() []string {
@@ -73,6 +224,18 @@ func (c *Config) ContextNames() []string {
 	return res
 }
 
+// FindContext returns the configured context with the name,
+// or a context opening the name as an URL
+func (c *Config) FindContext(name string) Context {
+	for _, cx := range c.Contexts {
+		if cx.Name == name {
+			return cx
+		}
+	}
+
+	return Context{Name: name, URL: name}
+}
+
 func (c *Config) Collapse(o *Config) {
 	cv := reflect.ValueOf(c).Elem()
 	ov := reflect.ValueOf(o).Elem()
@@ -86,6 +249,21 @@ func (c *Config) Collapse(o *Config) {
 		case reflect.Slice:
 			cf.Set(reflect.AppendSlice(cf, of))
 
+		case reflect.Map:
+			if of.Len() == 0 {
+				break
+			}
+
+			merged := reflect.MakeMapWithSize(of.Type(), cf.Len()+of.Len())
+			for _, m := range []reflect.Value{of, cf} { // Local keys win
+				iter := m.MapRange()
+				for iter.Next() {
+					merged.SetMapIndex(iter.Key(), iter.Value())
+				}
+			}
+
+			cf.Set(merged)
+
 		default:
 			if cf.IsZero() {
 				cf.Set(of)
@@ -116,15 +294,27 @@ func LoadSystemConfig(fs afero.Fs, base string) *Config {
 
 func LoadFilesystemConfig(fs afero.Fs, path string) (conf *Config, err error) {
 	if file, err := fs.Open(path); err == nil {
+		defer file.Close()
+
 		if conf, err = LoadConfigFrom(file); err != nil {
 			log.Println("Found config file while opening context but failed to parse it", err)
-			return nil, err
+			return nil, fmt.Errorf("%s: %w", path, err)
 		}
 	}
 
 	return
 }
 
+// WriteFilesystemConfig replaces the config file, leaving out unset options
+func WriteFilesystemConfig(fs afero.Fs, path string, conf *Config) error {
+	data, err := yaml.Marshal(conf)
+	if err != nil {
+		return err
+	}
+
+	return afero.WriteFile(fs, path, data, 0644)
+}
+
 func LoadConfigFrom(f io.Reader) (*Config, error) {
 	data, err := io.ReadAll(f)
 	if err != nil {
@@ -132,7 +322,7 @@ func LoadConfigFrom(f io.Reader) (*Config, error) {
 	}
 
 	var c Config
-	if yaml.Unmarshal(data, &c) != nil {
+	if err := yaml.Unmarshal(data, &c); err != nil {
 		return nil, err
 	}
 
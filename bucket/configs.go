@@ -0,0 +1,342 @@
+package bucket
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	ConfigHistoryFolder = ".bucket/configs"
+
+	// Bigger files in config folders are data, not configs
+	MaxConfigSize = 1 << 20
+
+	configLogFolder = "log"
+	configIDLength  = 12
+)
+
+var (
+	ErrConfigNotTracked = errors.New("no config folder for plugin")
+	ErrNoConfigSnapshot = errors.New("no config snapshot")
+)
+
+// TrackedConfig links a plugin config folder to the plugin owning it,
+// the folder is named after the descriptor name
+type TrackedConfig struct {
+	Plugin *LocalPlugin
+	Folder string
+}
+
+func (t TrackedConfig) Name() string {
+	return t.Plugin.GetName()
+}
+
+// ConfigSnapshot records the config files of a plugin by their object sum,
+// objects are stored once in the history however many snapshots use them
+type ConfigSnapshot struct {
+	ID      string            `json:"id"`
+	Created time.Time         `json:"created"`
+	Plugin  string            `json:"plugin"`
+	Files   map[string]string `json:"files"`
+}
+
+// ConfigChange is a config file that differs, with nil contents where it's missing
+type ConfigChange struct {
+	Path string
+	Old  []byte
+	New  []byte
+}
+
+// TrackedConfigs lists the plugin config folders with their plugin
+func (c *OpenContext) TrackedConfigs() ([]TrackedConfig, error) {
+	if c.Platform == nil {
+		return nil, errors.New("no platform detected")
+	}
+
+	folder := c.Platform.PluginsFolder()
+	if ok, err := c.Fs.DirExists(folder); err != nil || !ok {
+		return nil, err
+	}
+
+	files, err := c.Fs.ReadDir(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	// Some plugins lowercase their folder
+	dirs := make(map[string]string)
+	for _, f := range files {
+		if f.IsDir() {
+			dirs[strings.ToLower(f.Name())] = f.Name()
+		}
+	}
+
+	plugins, _, err := c.Platform.Plugins()
+	if err != nil && len(plugins) == 0 {
+		return nil, err
+	}
+
+	var tracked []TrackedConfig
+	for _, pl := range plugins {
+		local, ok := pl.(*LocalPlugin)
+		if !ok {
+			continue
+		}
+
+		if dir, ok := dirs[strings.ToLower(pl.GetName())]; ok {
+			tracked = append(tracked, TrackedConfig{Plugin: local, Folder: path.Join(folder, dir)})
+		}
+	}
+
+	slices.SortFunc(tracked, func(a, b TrackedConfig) int {
+		return strings.Compare(strings.ToLower(a.Name()), strings.ToLower(b.Name()))
+	})
+
+	return tracked, nil
+}
+
+// TrackedConfig finds the config folder of a plugin, ignoring case
+func (c *OpenContext) TrackedConfig(plugin string) (TrackedConfig, error) {
+	tracked, err := c.TrackedConfigs()
+	if err != nil {
+		return TrackedConfig{}, err
+	}
+
+	for _, t := range tracked {
+		if strings.EqualFold(t.Name(), plugin) {
+			return t, nil
+		}
+	}
+
+	return TrackedConfig{}, fmt.Errorf("%w %s", ErrConfigNotTracked, plugin)
+}
+
+// ConfigFiles reads the config files of a folder, by their path in it
+func (c *OpenContext) ConfigFiles(folder string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	if ok, err := c.Fs.DirExists(folder); err != nil || !ok {
+		return files, err
+	}
+
+	err := c.Fs.Walk(folder, func(name string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || info.Size() > MaxConfigSize ||
+			!slices.Contains(BackupConfigExts, strings.ToLower(path.Ext(name))) {
+			return err
+		}
+
+		data, err := c.Fs.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		files[strings.TrimPrefix(filepath.ToSlash(name), folder+"/")] = data
+		return nil
+	})
+
+	return files, err
+}
+
+func (c *OpenContext) configHistory() *ChunkStore {
+	return NewChunkStore(c.Fs, ConfigHistoryFolder)
+}
+
+func (c *OpenContext) configLogPath(plugin string) string {
+	return path.Join(ConfigHistoryFolder, configLogFolder, strings.ToLower(plugin)+".json")
+}
+
+// ConfigLog lists the config snapshots of a plugin, newest first
+func (c *OpenContext) ConfigLog(plugin string) ([]ConfigSnapshot, error) {
+	data, err := c.Fs.ReadFile(c.configLogPath(plugin))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var log []ConfigSnapshot
+	if err := json.Unmarshal(data, &log); err != nil {
+		return nil, fmt.Errorf("config log of %s: %w", plugin, err)
+	}
+
+	slices.SortStableFunc(log, func(a, b ConfigSnapshot) int {
+		return b.Created.Compare(a.Created)
+	})
+
+	return log, nil
+}
+
+// FindConfigSnapshot finds a snapshot by its id or a prefix of it, the latest if empty
+func (c *OpenContext) FindConfigSnapshot(plugin string, id string) (*ConfigSnapshot, error) {
+	log, err := c.ConfigLog(plugin)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range log {
+		if strings.HasPrefix(s.ID, id) {
+			return &s, nil
+		}
+	}
+
+	if id == "" {
+		return nil, fmt.Errorf("%w of %s", ErrNoConfigSnapshot, plugin)
+	}
+
+	return nil, fmt.Errorf("%w %s of %s", ErrNoConfigSnapshot, id, plugin)
+}
+
+// SnapshotConfig stores the current configs of a plugin in the
+// history, it returns nil if nothing changed since the last snapshot
+func (c *OpenContext) SnapshotConfig(t TrackedConfig) (*ConfigSnapshot, error) {
+	files, err := c.ConfigFiles(t.Folder)
+	if err != nil {
+		return nil, err
+	}
+
+	log, err := c.ConfigLog(t.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	store := c.configHistory()
+	snap := ConfigSnapshot{Created: time.Now().UTC(), Plugin: t.Name(), Files: make(map[string]string)}
+	for name, data := range files {
+		if snap.Files[name], _, err = store.PutChunk(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(log) > 0 && maps.Equal(log[0].Files, snap.Files) {
+		return nil, nil
+	}
+
+	// Like commits, identified by their content and parent
+	h := sha256.New()
+	if len(log) > 0 {
+		h.Write([]byte(log[0].ID))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(snap.Files)) {
+		fmt.Fprintf(h, "%s %s\n", snap.Files[name], name)
+	}
+
+	fmt.Fprint(h, snap.Created.UnixNano())
+	snap.ID = hex.EncodeToString(h.Sum(nil))[:configIDLength]
+
+	data, err := json.MarshalIndent(append([]ConfigSnapshot{snap}, log...), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := store.writeFile(c.configLogPath(t.Name()), data); err != nil {
+		return nil, err
+	}
+
+	return &snap, nil
+}
+
+// SnapshotFiles reads back the config files of a snapshot
+func (c *OpenContext) SnapshotFiles(snap *ConfigSnapshot) (map[string][]byte, error) {
+	store := c.configHistory()
+
+	files := make(map[string][]byte, len(snap.Files))
+	for name, sum := range snap.Files {
+		data, err := store.GetChunk(sum)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		files[name] = data
+	}
+
+	return files, nil
+}
+
+// RevertConfig puts back the configs of a snapshot, the latest if the id is
+// empty. The current configs are snapshotted first so the revert can be undone
+func (c *OpenContext) RevertConfig(t TrackedConfig, id string) (*ConfigSnapshot, error) {
+	snap, err := c.FindConfigSnapshot(t.Name(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := c.SnapshotFiles(snap)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.SnapshotConfig(t); err != nil {
+		return nil, err
+	}
+
+	current, err := c.ConfigFiles(t.Folder)
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range current {
+		if _, ok := files[name]; !ok {
+			if err := c.Fs.Remove(path.Join(t.Folder, name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for name, data := range files {
+		target := path.Join(t.Folder, name)
+		if err := c.Fs.MkdirAll(path.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+
+		if err := c.Fs.WriteFile(target, data, 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	return snap, nil
+}
+
+// DiffConfigFiles lists the files changed between two sets of configs, by path
+func DiffConfigFiles(old, new map[string][]byte) []ConfigChange {
+	names := slices.Sorted(maps.Keys(old))
+	for name := range new {
+		if _, ok := old[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	slices.Sort(names)
+
+	var changes []ConfigChange
+	for _, name := range names {
+		a, aok := old[name]
+		b, bok := new[name]
+
+		if aok && bok && string(a) == string(b) {
+			continue
+		}
+
+		// Present but empty files aren't missing
+		if aok && a == nil {
+			a = []byte{}
+		}
+
+		if bok && b == nil {
+			b = []byte{}
+		}
+
+		changes = append(changes, ConfigChange{Path: name, Old: a, New: b})
+	}
+
+	return changes
+}
@@ -0,0 +1,111 @@
+package bucket
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/MRtecno98/afero"
+)
+
+type testDescriptor struct {
+	name string
+}
+
+func (d testDescriptor) GetName() string        { return d.name }
+func (d testDescriptor) GetIdentifier() string  { return strings.ToLower(d.name) }
+func (d testDescriptor) GetVersion() string     { return "1.0" }
+func (d testDescriptor) GetAuthors() []string   { return nil }
+func (d testDescriptor) GetDescription() string { return "" }
+func (d testDescriptor) GetWebsite() string     { return "" }
+
+// pluginsPlatform is a platform with a fixed list of plugins and load errors
+type pluginsPlatform struct {
+	stubPlatform
+	plugins []Plugin
+	errs    []error
+}
+
+func (p *pluginsPlatform) Plugins() ([]Plugin, []error, error) { return p.plugins, p.errs, nil }
+
+func TestConfigHistory(t *testing.T) {
+	// The config log is rewritten on every snapshot, like on SFTP servers
+	fs := afero.Afero{Fs: noOverwriteFs{afero.NewMemMapFs()}}
+	fs.MkdirAll("plugins/essentials", 0755)
+	fs.WriteFile("plugins/essentials/config.yml", []byte("locale: en\nmotd: hi\n"), 0644)
+	fs.WriteFile("plugins/essentials/userdata.db", []byte("data"), 0644)
+
+	plt := &pluginsPlatform{plugins: []Plugin{
+		&LocalPlugin{PluginDescriptor: testDescriptor{"Essentials"}},
+		&LocalPlugin{PluginDescriptor: testDescriptor{"NoConfigs"}},
+	}}
+
+	c := &OpenContext{Fs: fs, Platform: plt, LocalConfig: &Config{}}
+
+	// Folders are matched to the descriptor name, ignoring case
+	tracked, err := c.TrackedConfigs()
+	if err != nil || len(tracked) != 1 || tracked[0].Folder != "plugins/essentials" {
+		t.Fatalf("wrong tracked configs: %v %v", tracked, err)
+	}
+
+	first, err := c.SnapshotConfig(tracked[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first.Files) != 1 {
+		t.Fatalf("expected only config.yml in the snapshot, got %v", first.Files)
+	}
+
+	if again, err := c.SnapshotConfig(tracked[0]); err != nil || again != nil {
+		t.Fatalf("unchanged configs snapshotted again: %v %v", again, err)
+	}
+
+	fs.WriteFile("plugins/essentials/config.yml", []byte("locale: it\nmotd: hi\n"), 0644)
+	fs.WriteFile("plugins/essentials/kits.yml", []byte("kits: {}\n"), 0644)
+
+	snap, _ := c.FindConfigSnapshot("essentials", "")
+	base, err := c.SnapshotFiles(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current, _ := c.ConfigFiles(tracked[0].Folder)
+	changes := DiffConfigFiles(base, current)
+	if len(changes) != 2 || changes[0].Path != "config.yml" || changes[1].Old != nil {
+		t.Fatalf("wrong changes: %+v", changes)
+	}
+
+	if _, err := c.RevertConfig(tracked[0], first.ID[:6]); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := fs.ReadFile("plugins/essentials/config.yml"); string(data) != "locale: en\nmotd: hi\n" {
+		t.Fatalf("config not reverted: %q", data)
+	}
+
+	if ok, _ := fs.Exists("plugins/essentials/kits.yml"); ok {
+		t.Fatal("config added after the snapshot not removed")
+	}
+
+	// The reverted state was snapshotted first
+	if history, _ := c.ConfigLog("Essentials"); len(history) != 2 {
+		t.Fatalf("expected the pre-revert snapshot in the log, got %d", len(history))
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	b := "a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+
+	expected := "--- old\n+++ new\n" +
+		"@@ -1,7 +1,7 @@\n a\n b\n c\n-d\n+D\n e\n f\n g\n" +
+		"@@ -10,3 +10,4 @@\n j\n k\n l\n+m\n"
+
+	if diff := UnifiedDiff("old", "new", []byte(a), []byte(b)); diff != expected {
+		t.Fatalf("wrong diff:\n%s", diff)
+	}
+
+	if diff := UnifiedDiff("old", "new", []byte(a), []byte(a)); diff != "" {
+		t.Fatalf("diff of equal texts: %s", diff)
+	}
+}
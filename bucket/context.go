@@ -1,10 +1,12 @@
 package bucket
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"slices"
+	"sync"
 
 	"github.com/MRtecno98/afero"
 	"github.com/MRtecno98/afero/resolver"
@@ -19,6 +21,9 @@ var DefaultRepositories = [...]string{"spigotmc", "modrinth"}
 type Context struct {
 	Name string `yaml:"name"`
 	URL  string `yaml:"url"`
+
+	// Substituted as ${NAME} in the config overlays of the context
+	Variables map[string]string `yaml:"variables,omitempty"`
 }
 
 type OpenContext struct {
@@ -29,6 +34,24 @@ type OpenContext struct {
 	LocalConfig  *Config
 	Platform     Platform
 	Repositories map[string]NamedRepository
+
+	// Repository names in configuration order
+	repositoryOrder []string
+
+	// Cancelled when the workspace is interrupted
+	Lock context.Context
+
+	comparator     *Comparator
+	comparatorOnce sync.Once
+
+	secrets     []SecretProvider
+	secretsErr  error
+	secretsOnce sync.Once
+
+	serverJars     []*ServerJar
+	serverJarsErr  error
+	serverJarsOnce sync.Once
+	serverJar      *ServerJar // Matched by the platform detector
 }
 
 type Workspace struct {
@@ -39,6 +62,11 @@ func (c *OpenContext) RunTask(task *Task) (error, bool) {
 	var newline, n bool
 	var err error
 
+	// Interrupted workspaces don't start new tasks
+	if err := c.Lock.Err(); err != nil {
+		return fmt.Errorf("%s: %v", c.Name, err), false
+	}
+
 	for _, t := range task.Depends() {
 		err, n = c.RunTask(t)
 		newline = newline || n
@@ -72,7 +100,7 @@ func (c *OpenContext) Run(name string, action TaskFunc) (error, bool) {
 
 	fmt.Printf(":%s [%s]\n", name, c.Name)
 
-	out := util.NewLookbackCountingWriter(os.Stdout, 2)
+	out := util.NewLookbackCountingWriter(NewMaskingWriter(os.Stdout), 2)
 	logger := log.New(out, "", log.Lmsgprefix)
 
 	err := action(c, logger)
@@ -121,29 +149,39 @@ func (w *Workspace) RunTaskWithContext(task *Task) error {
 	}
 }
 
+// CloseWorkspace closes every context, even if the workspace was interrupted
 func (w *Workspace) CloseWorkspace() {
-	w.RunWithContext("close", func(c *OpenContext, log *log.Logger) error {
-		c.CloseContext()
-		return nil
-	})
+	var newline bool = false
+
+	for _, c := range w.Contexts {
+		_, n := c.Run("close", func(c *OpenContext, log *log.Logger) error {
+			c.CloseContext()
+			return nil
+		})
+
+		newline = newline || n
+	}
+
+	if !newline && len(w.Contexts) > 1 {
+		fmt.Println()
+	}
 }
 
-func (c Context) OpenContext() (*OpenContext, error) {
+func (c Context) OpenContext(lock context.Context) (*OpenContext, error) {
 	fs, err := resolver.OpenUrl(c.URL)
 	if err != nil {
 		return nil, err
 	}
 
-	var conf *Config = &Config{}
-	conf, err = LoadFilesystemConfig(fs, ConfigName)
-	if err == nil {
-		if conf == nil {
-			conf = &Config{}
-		}
-
-		conf.Collapse(GlobalConfig) // Also add base options
+	conf, err := LoadFilesystemConfig(fs, ConfigName)
+	if err != nil {
+		return nil, err
+	} else if conf == nil {
+		conf = &Config{}
 	}
 
+	conf.Collapse(GlobalConfig) // Also add base options
+
 	sumdb, err := LoadSumDB(conf.SumDB)
 	if err != nil {
 		return nil, err
@@ -151,6 +189,7 @@ func (c Context) OpenContext() (*OpenContext, error) {
 
 	ctx := &OpenContext{Context: c, Fs: afero.Afero{Fs: fs},
 		LocalConfig:    conf,
+		Lock:           lock,
 		Repositories:   make(map[string]NamedRepository),
 		PluginDatabase: sumdb}
 
@@ -160,6 +199,36 @@ func (c Context) OpenContext() (*OpenContext, error) {
 		ctx.InitializeDatabase)
 }
 
+// OpenShallow only opens the filesystem, the config and the platform,
+// to read another context without its database and repositories
+func (c Context) OpenShallow(lock context.Context) (*OpenContext, error) {
+	fs, err := resolver.OpenUrl(c.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	conf, err := LoadFilesystemConfig(fs, ConfigName)
+	if err != nil {
+		return nil, err
+	} else if conf == nil {
+		conf = &Config{}
+	}
+
+	conf.Collapse(GlobalConfig)
+
+	ctx := &OpenContext{Context: c, Fs: afero.Afero{Fs: fs},
+		LocalConfig:  conf,
+		Lock:         lock,
+		Repositories: make(map[string]NamedRepository)}
+
+	if err := ctx.LoadPlatform(); err != nil {
+		fs.Close()
+		return nil, err
+	}
+
+	return ctx, nil
+}
+
 func LoadSumDB(name string) (PluginDatabase, error) {
 	switch name {
 	case SumDBSqlite:
@@ -172,7 +241,10 @@ func LoadSumDB(name string) (PluginDatabase, error) {
 }
 
 func (c *OpenContext) CloseContext() {
-	c.CloseDatabase()
+	if c.PluginDatabase != nil {
+		c.CloseDatabase()
+	}
+
 	c.Fs.Close()
 }
 
@@ -243,70 +315,12 @@ func (c *OpenContext) InitializeDatabase() error {
 	return c.PluginDatabase.InitializeDatabase(c)
 }
 
-func (c *OpenContext) ResolvePlugin(plugin Plugin) (RemotePlugin, error) {
-	var gerr error
-
-	if rem, ok := c.Plugins().GetAny(plugin.GetIdentifier()); ok {
-		return &rem, nil
-	}
-
-	for _, r := range c.Repositories {
-		if _, candidates, err := r.Resolve(plugin); err != nil {
-			gerr = multierror.Append(gerr, err)
-			continue
-		} else if len(candidates) > 0 {
-			keys := make([]float64, 0, len(candidates))
-			scores := make(map[float64]RemotePlugin)
-			for _, pl := range candidates {
-				if pl.Compatible(c.Platform.Type()) {
-					score := ComparisonIndex(plugin, pl)
-					keys = append(keys, score)
-					scores[score] = pl
-
-					if score >= 1.0 {
-						break
-					}
-				}
-			}
-
-			if DEBUG {
-				for _, v := range keys {
-					log.Printf("%s candidate: %s\t\t\tscore: %f [%s]\n",
-						plugin.GetName(), scores[v].GetName(), v, scores[v].GetRepository().Provider())
-				}
-			}
-
-			if len(keys) == 0 {
-				gerr = multierror.Append(gerr, fmt.Errorf(
-					"%d candidates found for \"%s\" but none are compatible with platform \"%s\"",
-					len(candidates), plugin.GetName(), c.Platform.Type().Name))
-				continue
-			}
-
-			slices.Sort(keys)
-			match := keys[len(keys)-1]
-
-			if match < SimilarityTreshold {
-				gerr = multierror.Append(gerr, fmt.Errorf(
-					"%d candidates found for \"%s\" but none satisfy similarity treshold, closest match was %f",
-					len(candidates), plugin.GetName(), match))
-				continue
-			}
-
-			if local, ok := plugin.(*LocalPlugin); ok {
-				res := CachedMatch(local, scores[match], r, match)
-				if err := c.SavePlugin(res); err != nil {
-					return nil, err
-				}
-
-				return &res, nil
-			}
-
-			return scores[match], nil
-		}
-	}
+func (c *OpenContext) Comparator() *Comparator {
+	c.comparatorOnce.Do(func() {
+		c.comparator = NewComparator(c.Config().Matching)
+	})
 
-	return nil, gerr
+	return c.comparator
 }
 
 func (c *OpenContext) RepositoryByNameOrProvider(name string) *NamedRepository {
@@ -322,7 +336,7 @@ func (c *OpenContext) RepositoryByNameOrProvider(name string) *NamedRepository {
 }
 
 func (c *OpenContext) RepositoryByProvider(provider string) *NamedRepository {
-	for _, v := range c.Repositories {
+	for _, v := range c.SortedRepositories() {
 		if v.Repository.Provider() == provider {
 			return &v
 		}
@@ -344,6 +358,10 @@ func (c *OpenContext) LoadRepositories() error {
 		if r, err := v.MakeRepository(c); err != nil {
 			return err
 		} else {
+			if _, ok := c.Repositories[v.GetName()]; !ok {
+				c.repositoryOrder = append(c.repositoryOrder, v.GetName())
+			}
+
 			c.Repositories[v.GetName()] = *r
 		}
 	}
@@ -351,13 +369,49 @@ func (c *OpenContext) LoadRepositories() error {
 	return nil
 }
 
-func CreateWorkspace(contexts ...Context) (*Workspace, error) {
+// ReloadConfig reads the config file again after it was replaced,
+// rebuilding the repositories from it
+func (c *OpenContext) ReloadConfig() error {
+	conf, err := LoadFilesystemConfig(c.Fs, ConfigName)
+	if err != nil {
+		return err
+	} else if conf == nil {
+		conf = &Config{}
+	}
+
+	conf.Collapse(GlobalConfig)
+
+	c.LocalConfig = conf
+	c.Repositories = make(map[string]NamedRepository)
+	c.repositoryOrder = nil
+
+	return c.LoadRepositories()
+}
+
+// SortedRepositories lists the repositories from the highest priority,
+// repositories with the same priority keep the configuration order
+func (c *OpenContext) SortedRepositories() []NamedRepository {
+	repos := make([]NamedRepository, 0, len(c.Repositories))
+	for _, name := range c.repositoryOrder {
+		if r, ok := c.Repositories[name]; ok {
+			repos = append(repos, r)
+		}
+	}
+
+	slices.SortStableFunc(repos, func(a, b NamedRepository) int {
+		return b.Priority - a.Priority
+	})
+
+	return repos
+}
+
+func CreateWorkspace(lock context.Context, contexts ...Context) (*Workspace, error) {
 	opened := make([]*OpenContext, len(contexts))
 
 	for i, v := range contexts {
 		err := Parallelize(GlobalConfig.Multithread,
 			func() error {
-				op, err := v.OpenContext()
+				op, err := v.OpenContext(lock)
 				if err != nil {
 					return err
 				} else {
@@ -1,9 +1,13 @@
 package bucket
 
 import (
+	"context"
+	"slices"
 	"testing"
 
 	"github.com/MRtecno98/afero"
+
+	_ "github.com/mattn/go-sqlite3"
 )
 
 var oc *OpenContext = &OpenContext{
@@ -15,6 +19,7 @@ var oc *OpenContext = &OpenContext{
 	Fs:             afero.Afero{},
 	PluginDatabase: NewSqliteDatabase(),
 	Repositories:   nil,
+	Lock:           context.Background(),
 
 	LocalConfig: &Config{
 		ActiveContexts: []string{"test"},
@@ -35,3 +40,50 @@ func init() {
 func TestResolve(t *testing.T) {
 
 }
+
+func TestSortedRepositories(t *testing.T) {
+	c := &OpenContext{
+		Repositories: map[string]NamedRepository{
+			"a": {RepositoryConfig: RepositoryConfig{Name: "a"}},
+			"b": {RepositoryConfig: RepositoryConfig{Name: "b", Priority: 10}},
+			"c": {RepositoryConfig: RepositoryConfig{Name: "c"}},
+		},
+
+		repositoryOrder: []string{"c", "a", "b"},
+		LocalConfig: &Config{Plugins: map[string]PluginConfig{
+			"Essentials": {Repository: "a"},
+		}},
+	}
+
+	names := func(repos []NamedRepository) []string {
+		res := make([]string, len(repos))
+		for i, r := range repos {
+			res[i] = r.GetName()
+		}
+
+		return res
+	}
+
+	if got := names(c.SortedRepositories()); !slices.Equal(got, []string{"b", "c", "a"}) {
+		t.Fatalf("unexpected repository order %v", got)
+	}
+
+	pl := &CachedPlugin{CachedRecord: CachedRecord{Name: "Essentials"}}
+	if got := names(c.resolutionOrder(pl)); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Fatalf("unexpected resolution order %v", got)
+	}
+}
+
+// Malformed configs must not be mistaken for missing ones and overwritten
+func TestLoadMalformedConfig(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, ConfigName, []byte("platform: [paper\n"), 0644)
+
+	if conf, err := LoadFilesystemConfig(fs, ConfigName); err == nil {
+		t.Fatalf("malformed config loaded: %+v", conf)
+	}
+
+	if conf, err := LoadFilesystemConfig(fs, "missing.yml"); err != nil || conf != nil {
+		t.Fatalf("missing config should load as nil: %+v %v", conf, err)
+	}
+}
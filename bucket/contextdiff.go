@@ -0,0 +1,228 @@
+package bucket
+
+import (
+	"cmp"
+	"errors"
+	"maps"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// PluginState is an installed plugin as compared between contexts
+type PluginState struct {
+	Name       string
+	File       string
+	Version    string
+	Repository string
+	Identifier string
+	Sha256     string
+}
+
+// PluginDiff is a plugin that differs between two contexts, A or B is nil
+// when the plugin is only installed in the other one
+type PluginDiff struct {
+	Key  string
+	A, B *PluginState
+}
+
+func (d PluginDiff) Name() string {
+	if d.A != nil {
+		return d.A.Name
+	}
+
+	return d.B.Name
+}
+
+// VersionChanged tells if the plugin is in both contexts with different versions
+func (d PluginDiff) VersionChanged() bool {
+	return d.A != nil && d.B != nil && !sameVersion(d.A.Version, d.B.Version)
+}
+
+// ConfigDiff is a tracked config folder with the files changed from A to B
+type ConfigDiff struct {
+	Plugin  string
+	Changes []ConfigChange
+}
+
+// ContextDiff is the comparison of the servers of two contexts
+type ContextDiff struct {
+	A, B *OpenContext
+
+	Plugins []PluginDiff
+	Configs []ConfigDiff
+}
+
+func (d *ContextDiff) Empty() bool {
+	return len(d.Plugins) == 0 && len(d.Configs) == 0 &&
+		d.A.PlatformName() == d.B.PlatformName() && d.A.GameVersion() == d.B.GameVersion()
+}
+
+// PluginStates lists the installed plugins by key, the resolved repository
+// and remote identifier when known, or the lowercase name otherwise
+func (c *OpenContext) PluginStates() (map[string]PluginState, error) {
+	if c.Platform == nil {
+		return nil, errors.New("no platform detected")
+	}
+
+	plugins, _, err := c.Platform.Plugins()
+	if err != nil && len(plugins) == 0 {
+		return nil, err
+	}
+
+	states := make(map[string]PluginState, len(plugins))
+	for _, pl := range plugins {
+		state := PluginState{Name: pl.GetName()}
+		if v, ok := pl.(Versionable); ok {
+			state.Version = v.GetVersion()
+		}
+
+		if local, ok := pl.(*LocalPlugin); ok && local.File != nil {
+			state.File = path.Base(filepath.ToSlash(local.File.Name()))
+			if state.Sha256, err = local.Hash(); err != nil {
+				return nil, err
+			}
+		}
+
+		key := "name:" + strings.ToLower(pl.GetName())
+		if c.PluginDatabase != nil {
+			if rec, ok := c.Plugins().GetFirst(pl.GetIdentifier()); ok {
+				state.Repository, state.Identifier = rec.Repository.GetName(), rec.RemoteIdentifier
+				key = state.Repository + ":" + state.Identifier
+			}
+		}
+
+		states[key] = state
+	}
+
+	return states, nil
+}
+
+// DiffContexts compares the plugins and the tracked configs of two contexts
+func DiffContexts(a, b *OpenContext) (*ContextDiff, error) {
+	diff := &ContextDiff{A: a, B: b}
+
+	as, err := a.PluginStates()
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err := b.PluginStates()
+	if err != nil {
+		return nil, err
+	}
+
+	// Plugins resolved on one side only, or through different
+	// repositories, are matched by name
+	for key, state := range as {
+		if _, ok := bs[key]; ok {
+			continue
+		}
+
+		for other, ostate := range bs {
+			if _, ok := as[other]; !ok && strings.EqualFold(ostate.Name, state.Name) {
+				delete(bs, other)
+				bs[key] = ostate
+				break
+			}
+		}
+	}
+
+	keys := slices.Collect(maps.Keys(as))
+	for key := range bs {
+		if _, ok := as[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	for _, key := range keys {
+		pd := PluginDiff{Key: key}
+		if s, ok := as[key]; ok {
+			pd.A = &s
+		}
+
+		if s, ok := bs[key]; ok {
+			pd.B = &s
+		}
+
+		if pd.A != nil && pd.B != nil && pd.A.Sha256 == pd.B.Sha256 && !pd.VersionChanged() {
+			continue
+		}
+
+		diff.Plugins = append(diff.Plugins, pd)
+	}
+
+	slices.SortFunc(diff.Plugins, func(x, y PluginDiff) int {
+		return strings.Compare(strings.ToLower(x.Name()), strings.ToLower(y.Name()))
+	})
+
+	if diff.Configs, err = diffTrackedConfigs(a, b); err != nil {
+		return nil, err
+	}
+
+	return diff, nil
+}
+
+func diffTrackedConfigs(a, b *OpenContext) ([]ConfigDiff, error) {
+	folders := func(c *OpenContext) (map[string]TrackedConfig, error) {
+		tracked, err := c.TrackedConfigs()
+		if err != nil {
+			return nil, err
+		}
+
+		names := make(map[string]TrackedConfig, len(tracked))
+		for _, t := range tracked {
+			names[strings.ToLower(t.Name())] = t
+		}
+
+		return names, nil
+	}
+
+	af, err := folders(a)
+	if err != nil {
+		return nil, err
+	}
+
+	bf, err := folders(b)
+	if err != nil {
+		return nil, err
+	}
+
+	names := slices.Collect(maps.Keys(af))
+	for name := range bf {
+		if _, ok := af[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	slices.Sort(names)
+
+	var diffs []ConfigDiff
+	for _, name := range names {
+		var old, new map[string][]byte
+		plugin := ""
+
+		if t, ok := af[name]; ok {
+			if old, err = a.ConfigFiles(t.Folder); err != nil {
+				return nil, err
+			}
+
+			plugin = t.Name()
+		}
+
+		if t, ok := bf[name]; ok {
+			if new, err = b.ConfigFiles(t.Folder); err != nil {
+				return nil, err
+			}
+
+			plugin = cmp.Or(plugin, t.Name())
+		}
+
+		if changes := DiffConfigFiles(old, new); len(changes) > 0 {
+			diffs = append(diffs, ConfigDiff{Plugin: plugin, Changes: changes})
+		}
+	}
+
+	return diffs, nil
+}
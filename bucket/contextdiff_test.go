@@ -0,0 +1,95 @@
+package bucket
+
+import (
+	"testing"
+
+	"github.com/MRtecno98/afero"
+)
+
+type versionedDescriptor struct {
+	testDescriptor
+	version string
+}
+
+func (d versionedDescriptor) GetVersion() string { return d.version }
+
+func TestDiffContexts(t *testing.T) {
+	open := func(name string, files map[string]string, plugins ...Plugin) *OpenContext {
+		fs := afero.Afero{Fs: afero.NewMemMapFs()}
+		for path, data := range files {
+			fs.WriteFile(path, []byte(data), 0644)
+		}
+
+		return &OpenContext{Context: Context{Name: name}, Fs: fs,
+			Platform: &pluginsPlatform{plugins: plugins}, PluginDatabase: NewSqliteDatabase(), LocalConfig: &Config{}}
+	}
+
+	jar := func(fs afero.Afero, name, data string) afero.File {
+		fs.WriteFile(name, []byte(data), 0644)
+		f, _ := fs.Open(name)
+		return f
+	}
+
+	a := open("lobby", map[string]string{
+		"plugins/Essentials/config.yml": "locale: en\n",
+		"plugins/LuckPerms/config.yml":  "storage: h2\n",
+	})
+
+	b := open("survival", map[string]string{
+		"plugins/Essentials/config.yml": "locale: it\n",
+		"plugins/LuckPerms/config.yml":  "storage: h2\n",
+	})
+
+	a.Platform.(*pluginsPlatform).plugins = []Plugin{
+		&LocalPlugin{PluginDescriptor: versionedDescriptor{testDescriptor{"Essentials"}, "2.20.0"}},
+		&LocalPlugin{PluginDescriptor: testDescriptor{"LuckPerms"}, File: jar(a.Fs, "plugins/LuckPerms.jar", "same")},
+		&LocalPlugin{PluginDescriptor: testDescriptor{"WorldEdit"}, File: jar(a.Fs, "plugins/WorldEdit.jar", "v1")},
+		&LocalPlugin{PluginDescriptor: testDescriptor{"OnlyLobby"}},
+	}
+
+	b.Platform.(*pluginsPlatform).plugins = []Plugin{
+		&LocalPlugin{PluginDescriptor: versionedDescriptor{testDescriptor{"Essentials"}, "2.21.0"}},
+		&LocalPlugin{PluginDescriptor: testDescriptor{"LuckPerms"}, File: jar(b.Fs, "plugins/LuckPerms.jar", "same")},
+		&LocalPlugin{PluginDescriptor: testDescriptor{"WorldEdit"}, File: jar(b.Fs, "plugins/WorldEdit.jar", "v2")},
+	}
+
+	resolved := func(c *OpenContext, local, repo, remote string) {
+		c.Plugins().Put(CachedPlugin{CachedRecord: CachedRecord{LocalIdentifier: local, RemoteIdentifier: remote},
+			Repository: NamedRepository{RepositoryConfig: RepositoryConfig{Name: repo}}})
+	}
+
+	// Resolved on one side only, and through different repositories
+	resolved(a, "essentials", "modrinth", "hXiIvTyT")
+	resolved(a, "worldedit", "modrinth", "1u6JkXh5")
+	resolved(b, "worldedit", "spigotmc", "13932")
+
+	diff, err := DiffContexts(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(diff.Plugins) != 3 {
+		t.Fatalf("expected 3 differing plugins, got %+v", diff.Plugins)
+	}
+
+	if p := diff.Plugins[0]; p.Name() != "Essentials" || !p.VersionChanged() {
+		t.Fatalf("version change not reported: %+v", p)
+	}
+
+	if p := diff.Plugins[1]; p.Name() != "OnlyLobby" || p.A == nil || p.B != nil {
+		t.Fatalf("missing plugin not reported: %+v", p)
+	}
+
+	if p := diff.Plugins[2]; p.Name() != "WorldEdit" || p.VersionChanged() || p.A.Sha256 == p.B.Sha256 {
+		t.Fatalf("jar change not reported: %+v", p)
+	}
+
+	if len(diff.Configs) != 1 || diff.Configs[0].Plugin != "Essentials" ||
+		len(diff.Configs[0].Changes) != 1 || diff.Configs[0].Changes[0].Path != "config.yml" {
+		t.Fatalf("wrong config changes: %+v", diff.Configs)
+	}
+
+	if diff.Empty() {
+		t.Fatal("diff reported as empty")
+	}
+}
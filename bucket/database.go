@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/MRtecno98/afero/sqlitevfs"
 )
@@ -20,12 +22,19 @@ type SqliteDatabase struct {
 	ctx  *OpenContext
 
 	plugins *SymmetricBiMap[string, CachedPlugin]
+
+	lock       sync.Mutex
+	unresolved map[string]UnresolvedRecord
+
+	// SQLite allows a single writer at a time
+	writeLock sync.Mutex
 }
 
 func NewNamedSqliteDatabase(name string) *SqliteDatabase {
 	return &SqliteDatabase{
-		Name:    name,
-		plugins: NewPluginBiMap(),
+		Name:       name,
+		plugins:    NewPluginBiMap(),
+		unresolved: make(map[string]UnresolvedRecord),
 	}
 }
 
@@ -63,7 +72,8 @@ func (db *SqliteDatabase) InitializeDatabase(ctx *OpenContext) error {
 }
 
 func (db *SqliteDatabase) LoadPluginDatabase() error {
-	rows, err := db.conn.Query(`SELECT * FROM plugins`)
+	rows, err := db.conn.Query(`SELECT identifier, remote_identifier, filename,
+		name, repository, confidence, authors, description, website, versions FROM plugins`)
 	if err != nil {
 		return err
 	}
@@ -74,15 +84,19 @@ func (db *SqliteDatabase) LoadPluginDatabase() error {
 		var plugin CachedPlugin
 		var authors string
 		var repo string
+		var versions sql.NullString
 
 		if err := rows.Scan(&plugin.LocalIdentifier,
 			&plugin.RemoteIdentifier, &plugin.File,
 			&plugin.Name, &repo, &plugin.Confidence, &authors,
-			&plugin.Description, &plugin.Website); err != nil {
+			&plugin.Description, &plugin.Website, &versions); err != nil {
 			return err
 		}
 
 		plugin.Authors = strings.Split(authors, ",")
+		if versions.String != "" {
+			plugin.Versions = strings.Split(versions.String, ",")
+		}
 		repository := db.ctx.RepositoryByNameOrProvider(repo)
 		if repository == nil {
 			log.Printf("warn: repository %s not found for plugin record %s\n", repo, plugin.LocalIdentifier)
@@ -90,14 +104,89 @@ func (db *SqliteDatabase) LoadPluginDatabase() error {
 		}
 
 		plugin.Repository = *repository
+		plugin.database = db
 
 		db.plugins.Put(plugin)
 	}
 
+	return db.loadUnresolved()
+}
+
+func (db *SqliteDatabase) loadUnresolved() error {
+	rows, err := db.conn.Query(`SELECT identifier, filename, hash, timestamp FROM unresolved`)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	for rows.Next() {
+		var rec UnresolvedRecord
+		var timestamp int64
+
+		if err := rows.Scan(&rec.LocalIdentifier, &rec.File, &rec.Hash, &timestamp); err != nil {
+			return err
+		}
+
+		rec.Timestamp = time.Unix(timestamp, 0)
+		db.unresolved[rec.LocalIdentifier] = rec
+	}
+
+	return rows.Err()
+}
+
+func (db *SqliteDatabase) GetUnresolved(identifier string) (UnresolvedRecord, bool) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	rec, ok := db.unresolved[identifier]
+	return rec, ok
+}
+
+func (db *SqliteDatabase) SaveUnresolved(record UnresolvedRecord) error {
+	db.writeLock.Lock()
+	defer db.writeLock.Unlock()
+
+	if _, err := db.conn.Exec(`REPLACE INTO unresolved
+		(identifier, filename, hash, timestamp) VALUES (?, ?, ?, ?)`,
+		record.LocalIdentifier, record.File, record.Hash, record.Timestamp.Unix()); err != nil {
+		return fmt.Errorf("unresolved save: %w", err)
+	}
+
+	db.lock.Lock()
+	db.unresolved[record.LocalIdentifier] = record
+	db.lock.Unlock()
+
+	return nil
+}
+
+func (db *SqliteDatabase) DeleteUnresolved(identifier string) error {
+	db.lock.Lock()
+	_, ok := db.unresolved[identifier]
+	delete(db.unresolved, identifier)
+	db.lock.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	db.writeLock.Lock()
+	defer db.writeLock.Unlock()
+
+	if _, err := db.conn.Exec(`DELETE FROM unresolved WHERE identifier = ?`, identifier); err != nil {
+		return fmt.Errorf("unresolved delete: %w", err)
+	}
+
 	return nil
 }
 
 func (db *SqliteDatabase) SavePlugin(plugin CachedPlugin) error {
+	db.writeLock.Lock()
+	defer db.writeLock.Unlock()
+
 	tx, err := db.conn.Begin()
 	if err != nil {
 		return err
@@ -105,7 +194,7 @@ func (db *SqliteDatabase) SavePlugin(plugin CachedPlugin) error {
 
 	defer tx.Rollback()
 
-	if err := db._savePlugin(plugin); err != nil {
+	if err := db._savePlugin(tx, plugin); err != nil {
 		return err
 	}
 
@@ -117,7 +206,7 @@ func (db *SqliteDatabase) SavePlugin(plugin CachedPlugin) error {
 	return nil
 }
 
-func (db *SqliteDatabase) _savePlugin(plugins ...CachedPlugin) error {
+func (db *SqliteDatabase) _savePlugin(tx *sql.Tx, plugins ...CachedPlugin) error {
 	var q strings.Builder
 	var args []any
 
@@ -125,17 +214,17 @@ func (db *SqliteDatabase) _savePlugin(plugins ...CachedPlugin) error {
 		return nil
 	}
 
-	args = make([]any, 0, len(plugins)*9)
+	args = make([]any, 0, len(plugins)*10)
 
 	q.WriteString(`REPLACE INTO plugins 
 		(identifier, remote_identifier, 
 		 filename, 
 		 name, repository, confidence,
-		 authors, description, website) 
+		 authors, description, website, versions) 
 		 VALUES `)
 
 	for i, plugin := range plugins {
-		q.WriteString("(?, ?, ?, ?, ?, ?, ?, ?, ?)")
+		q.WriteString("(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 		if i != len(plugins)-1 {
 			q.WriteString(", ")
 		}
@@ -144,10 +233,11 @@ func (db *SqliteDatabase) _savePlugin(plugins ...CachedPlugin) error {
 			plugin.LocalIdentifier, plugin.RemoteIdentifier, plugin.File,
 			plugin.GetName(), plugin.Repository.GetName(), plugin.Confidence,
 			strings.Join(plugin.GetAuthors(), ","),
-			plugin.GetDescription(), plugin.GetWebsite())
+			plugin.GetDescription(), plugin.GetWebsite(),
+			strings.Join(plugin.Versions, ","))
 	}
 
-	if _, err := db.conn.Exec(q.String(), args...); err != nil {
+	if _, err := tx.Exec(q.String(), args...); err != nil {
 		return fmt.Errorf("db save (no data was modified): %w", err)
 	}
 
@@ -155,12 +245,15 @@ func (db *SqliteDatabase) _savePlugin(plugins ...CachedPlugin) error {
 }
 
 func (db *SqliteDatabase) SavePluginDatabase() error {
+	db.writeLock.Lock()
+	defer db.writeLock.Unlock()
+
 	tx, err := db.conn.Begin()
 	if err != nil {
 		return err
 	}
 
-	if db._savePlugin(db.plugins.Values()...) != nil {
+	if db._savePlugin(tx, db.plugins.Values()...) != nil {
 		return tx.Rollback()
 	}
 
@@ -185,7 +278,22 @@ func (db *SqliteDatabase) InitializeTables() error {
 		confidence REAL,
 		authors TEXT,
 		description TEXT,
-		website TEXT
+		website TEXT,
+		versions TEXT
+	);`); err != nil {
+		return err
+	}
+
+	if err := migrateColumn(tx, "plugins", "versions", "TEXT"); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS unresolved (
+		identifier VARCHAR(255) PRIMARY KEY,
+		filename TEXT,
+		hash VARCHAR(64),
+		timestamp INTEGER
 	);`); err != nil {
 		return err
 	}
@@ -211,6 +319,32 @@ func (db *SqliteDatabase) InitializeTables() error {
 	return nil
 }
 
+// migrateColumn adds a column to tables created by older versions
+func migrateColumn(tx *sql.Tx, table, column, kind string) error {
+	rows, err := tx.Query(fmt.Sprintf("SELECT name FROM pragma_table_info('%s')", table))
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+
+		if name == column {
+			return nil
+		}
+	}
+
+	rows.Close()
+
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, kind))
+	return err
+}
+
 func (db *SqliteDatabase) DBSize() (int64, error) {
 	inf, err := db.ctx.Fs.Stat(DatabaseName)
 	if err != nil {
@@ -221,6 +355,9 @@ func (db *SqliteDatabase) DBSize() (int64, error) {
 }
 
 func (db *SqliteDatabase) CleanCache() error {
+	db.writeLock.Lock()
+	defer db.writeLock.Unlock()
+
 	if db.conn != nil {
 		if err := db.conn.Close(); err != nil {
 			return err
@@ -232,10 +369,14 @@ func (db *SqliteDatabase) CleanCache() error {
 	}
 
 	db.conn = nil
+	db.unresolved = make(map[string]UnresolvedRecord)
 	return nil
 }
 
 func (db *SqliteDatabase) CloseDatabase() error {
+	db.writeLock.Lock()
+	defer db.writeLock.Unlock()
+
 	if db.conn != nil {
 		if err := db.conn.Close(); err != nil {
 			return fmt.Errorf("close database: %w", err)
@@ -245,6 +386,7 @@ func (db *SqliteDatabase) CloseDatabase() error {
 	db.conn = nil
 	db.ctx = nil
 	db.plugins = nil
+	db.unresolved = nil
 
 	return nil
 }
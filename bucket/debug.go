@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"log"
 	"reflect"
-	"sync"
 	"unsafe"
 
 	"github.com/MRtecno98/afero"
@@ -56,47 +55,22 @@ func DebugRoutine(oc *OpenContext, logger *log.Logger) error {
 		return err
 	}
 
-	var wait sync.WaitGroup
-	wait.Add(len(pls))
-
-	var errs error = nil
-
-	for _, pli := range pls {
-		f := func(pl Plugin) {
-			defer wait.Done()
-			res, err := oc.ResolvePlugin(pl)
-			if err != nil {
-				multierror.Append(errs,
-					fmt.Errorf("error resolving plugin %s: %v", pl.GetName(), err))
-				return
-			}
-
-			ver, err := res.GetLatestVersion()
-			if err != nil {
-				multierror.Append(errs,
-					fmt.Errorf("error getting latest version for %s: %v", res.GetIdentifier(), err))
-				return
-			}
-
-			var ind float64
-			if c, ok := res.(*CachedPlugin); ok {
-				ind = c.Confidence
-			} else {
-				ind = ComparisonIndex(pl, res)
-			}
-
-			logger.Printf("found plugin: %s [%s] %s %s%s %f\n", pl.GetName(), res.GetRepository().Provider(), res.GetName(),
-				ver.GetName(), res.GetAuthors(), ind)
+	return oc.ResolvePlugins(oc.Lock, pls, func(pl Plugin, res RemotePlugin) error {
+		ver, err := res.GetLatestVersion(oc.Lock)
+		if err != nil {
+			return fmt.Errorf("error getting latest version for %s: %v", res.GetIdentifier(), err)
 		}
 
-		if GlobalConfig.Multithread {
-			go f(pli)
+		var ind float64
+		if c, ok := res.(*CachedPlugin); ok {
+			ind = c.Confidence
 		} else {
-			f(pli)
+			ind = oc.Comparator().Index(oc.Lock, pl, res)
 		}
-	}
 
-	wait.Wait()
+		logger.Printf("found plugin: %s [%s] %s %s%s %f\n", pl.GetName(), res.GetRepository().Provider(), res.GetName(),
+			ver.GetName(), res.GetAuthors(), ind)
 
-	return errs
+		return nil
+	})
 }
@@ -92,6 +92,19 @@ func DetectFromJarsDirs(dirs []string, context *OpenContext, filter func(jar *af
 	return false, nil
 }
 
+// DetectFiles checks if any of the files or directories exists in the context root
+func DetectFiles(context *OpenContext, names ...string) (bool, error) {
+	for _, name := range names {
+		if ok, err := context.Fs.Exists(name); err != nil {
+			return false, err
+		} else if ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
 func DetectJarPath(context *OpenContext, filter func(path string) bool) (bool, error) {
 	return DetectJarPathDirs([]string{""}, context, filter)
 }
@@ -0,0 +1,198 @@
+package bucket
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lines around the changes shown in unified diffs
+const DiffContext = 3
+
+// Over this many compared line pairs the changed region
+// is shown as a whole replacement instead
+const maxDiffCells = 16 << 20
+
+type DiffOp byte
+
+const (
+	DiffEqual  DiffOp = ' '
+	DiffDelete DiffOp = '-'
+	DiffInsert DiffOp = '+'
+)
+
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// SplitLines splits a text in lines without their terminators
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), "\n")
+}
+
+// DiffLines finds the lines to delete from a and to insert from b,
+// through their longest common subsequence
+func DiffLines(a, b []string) []DiffLine {
+	// Common prefix and suffix are kept out of the table
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	diff := make([]DiffLine, 0, len(a)+len(b))
+	for _, l := range a[:pre] {
+		diff = append(diff, DiffLine{DiffEqual, l})
+	}
+
+	diff = append(diff, diffMiddle(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+
+	for _, l := range a[len(a)-suf:] {
+		diff = append(diff, DiffLine{DiffEqual, l})
+	}
+
+	return diff
+}
+
+func diffMiddle(a, b []string) []DiffLine {
+	var diff []DiffLine
+	if len(a)*len(b) > maxDiffCells {
+		for _, l := range a {
+			diff = append(diff, DiffLine{DiffDelete, l})
+		}
+
+		for _, l := range b {
+			diff = append(diff, DiffLine{DiffInsert, l})
+		}
+
+		return diff
+	}
+
+	// lcs[i][j] is the common subsequence length of a[i:] and b[j:]
+	w := len(b) + 1
+	lcs := make([]int32, (len(a)+1)*w)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*w+j] = lcs[(i+1)*w+j+1] + 1
+			} else {
+				lcs[i*w+j] = max(lcs[(i+1)*w+j], lcs[i*w+j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, DiffLine{DiffEqual, a[i]})
+			i++
+			j++
+		case lcs[(i+1)*w+j] >= lcs[i*w+j+1]:
+			diff = append(diff, DiffLine{DiffDelete, a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{DiffInsert, b[j]})
+			j++
+		}
+	}
+
+	for ; i < len(a); i++ {
+		diff = append(diff, DiffLine{DiffDelete, a[i]})
+	}
+
+	for ; j < len(b); j++ {
+		diff = append(diff, DiffLine{DiffInsert, b[j]})
+	}
+
+	return diff
+}
+
+// UnifiedDiff formats the changes between two texts like diff -u, with
+// the known secrets masked. It's empty if they have the same lines
+func UnifiedDiff(from, to string, a, b []byte) string {
+	diff := DiffLines(SplitLines(string(a)), SplitLines(string(b)))
+
+	var out strings.Builder
+	for start := 0; start < len(diff); {
+		// Find the next change and extend the hunk while changes are close
+		first := start
+		for first < len(diff) && diff[first].Op == DiffEqual {
+			first++
+		}
+
+		if first == len(diff) {
+			break
+		}
+
+		begin := max(first-DiffContext, start)
+		end, equal := first, 0
+		for ; end < len(diff) && equal <= 2*DiffContext; end++ {
+			if diff[end].Op == DiffEqual {
+				equal++
+			} else {
+				equal = 0
+			}
+		}
+
+		end -= max(equal-DiffContext, 0)
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", from, to)
+		}
+
+		writeHunk(&out, diff, begin, end)
+		start = end
+	}
+
+	return MaskSecrets(out.String())
+}
+
+func writeHunk(out *strings.Builder, diff []DiffLine, begin, end int) {
+	// Line numbers of the hunk start on both sides
+	aline, bline := 1, 1
+	for _, l := range diff[:begin] {
+		if l.Op != DiffInsert {
+			aline++
+		}
+
+		if l.Op != DiffDelete {
+			bline++
+		}
+	}
+
+	alen, blen := 0, 0
+	for _, l := range diff[begin:end] {
+		if l.Op != DiffInsert {
+			alen++
+		}
+
+		if l.Op != DiffDelete {
+			blen++
+		}
+	}
+
+	// Empty ranges start at the line before, like diff does
+	if alen == 0 {
+		aline--
+	}
+
+	if blen == 0 {
+		bline--
+	}
+
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", aline, alen, bline, blen)
+	for _, l := range diff[begin:end] {
+		out.WriteByte(byte(l.Op))
+		out.WriteString(l.Text)
+		out.WriteByte('\n')
+	}
+}
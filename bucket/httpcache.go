@@ -0,0 +1,422 @@
+package bucket
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MRtecno98/afero"
+)
+
+const CacheFolder = "cache"
+
+// Default HTTP cache size limit in megabytes
+const DefaultCacheSize = 256
+
+const (
+	cacheMetaExt = ".meta"
+	cacheBodyExt = ".body"
+)
+
+// HTTPCache is an on-disk cache of HTTP responses, shared by all repositories
+type HTTPCache struct {
+	Fs      afero.Afero
+	MaxSize int64
+
+	lock sync.Mutex
+}
+
+type CacheEntry struct {
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Stored time.Time   `json:"stored"`
+	Size   int64       `json:"size"`
+}
+
+type CacheStats struct {
+	Entries int
+	Size    int64
+	MaxSize int64
+}
+
+// CachingTransport serves GET requests from an HTTPCache, revalidating stale
+// entries with the upstream server through conditional requests
+type CachingTransport struct {
+	Transport http.RoundTripper
+	Cache     *HTTPCache
+}
+
+var sharedCache *HTTPCache
+var sharedCacheOnce sync.Once
+
+func CacheDirectory() string {
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".bucket", CacheFolder)
+	}
+
+	return filepath.Join(os.TempDir(), "bucket-"+CacheFolder)
+}
+
+// SharedHTTPCache returns the cache in the user home directory,
+// its size is taken from the system configuration on first use
+func SharedHTTPCache() *HTTPCache {
+	sharedCacheOnce.Do(func() {
+		size := int64(GlobalConfig.CacheSize)
+		if size <= 0 {
+			size = DefaultCacheSize
+		}
+
+		sharedCache = NewHTTPCache(CacheDirectory(), size<<20)
+	})
+
+	return sharedCache
+}
+
+func NewHTTPCache(dir string, maxSize int64) *HTTPCache {
+	osfs := afero.NewOsFs()
+	osfs.MkdirAll(dir, 0755)
+
+	return &HTTPCache{
+		Fs:      afero.Afero{Fs: afero.NewBasePathFs(osfs, dir)},
+		MaxSize: maxSize,
+	}
+}
+
+func SharedTransport() http.RoundTripper {
+	return &CachingTransport{Transport: SharedRetryTransport(), Cache: SharedHTTPCache()}
+}
+
+func NewHTTPClient() *http.Client {
+	return &http.Client{Transport: SharedTransport()}
+}
+
+func CacheKey(url string) string {
+	h := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(h[:])
+}
+
+func (c *HTTPCache) Load(url string) (*CacheEntry, bool) {
+	data, err := c.Fs.ReadFile(CacheKey(url) + cacheMetaExt)
+	if err != nil {
+		return nil, false
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != url {
+		return nil, false
+	}
+
+	return &entry, true
+}
+
+func (c *HTTPCache) Body(entry *CacheEntry) (afero.File, error) {
+	return c.Fs.Open(CacheKey(entry.URL) + cacheBodyExt)
+}
+
+func (c *HTTPCache) Store(entry *CacheEntry, body []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := CacheKey(entry.URL)
+
+	if body != nil {
+		entry.Size = int64(len(body))
+
+		if err := c.writeAtomic(key+cacheBodyExt, body); err != nil {
+			return err
+		}
+	}
+
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := c.writeAtomic(key+cacheMetaExt, meta); err != nil {
+		return err
+	}
+
+	return c.enforceLimit()
+}
+
+func (c *HTTPCache) writeAtomic(name string, data []byte) error {
+	tmp := name + ".tmp"
+	if err := c.Fs.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return c.Fs.Rename(tmp, name)
+}
+
+// enforceLimit removes the oldest entries until the cache fits its size limit
+func (c *HTTPCache) enforceLimit() error {
+	if c.MaxSize <= 0 {
+		return nil
+	}
+
+	files, err := c.Fs.ReadDir("")
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, f := range files {
+		total += f.Size()
+	}
+
+	if total <= c.MaxSize {
+		return nil
+	}
+
+	bodies := slices.DeleteFunc(files, func(f os.FileInfo) bool {
+		return filepath.Ext(f.Name()) != cacheBodyExt
+	})
+
+	slices.SortFunc(bodies, func(a, b os.FileInfo) int {
+		return a.ModTime().Compare(b.ModTime())
+	})
+
+	for _, f := range bodies {
+		if total <= c.MaxSize {
+			break
+		}
+
+		key := strings.TrimSuffix(f.Name(), cacheBodyExt)
+		c.Fs.Remove(key + cacheMetaExt)
+		if err := c.Fs.Remove(f.Name()); err != nil {
+			return err
+		}
+
+		total -= f.Size()
+	}
+
+	return nil
+}
+
+func (c *HTTPCache) Stats() (CacheStats, error) {
+	stats := CacheStats{MaxSize: c.MaxSize}
+
+	files, err := c.Fs.ReadDir("")
+	if err != nil {
+		return stats, err
+	}
+
+	for _, f := range files {
+		if filepath.Ext(f.Name()) == cacheMetaExt {
+			stats.Entries++
+		}
+
+		stats.Size += f.Size()
+	}
+
+	return stats, nil
+}
+
+func (c *HTTPCache) Clear() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	files, err := c.Fs.ReadDir("")
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if err := c.Fs.RemoveAll(f.Name()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Fresh tells if the entry can be served without revalidation
+func (e *CacheEntry) Fresh() bool {
+	cc := ParseCacheControl(e.Header.Get("Cache-Control"))
+	if _, ok := cc["no-cache"]; ok {
+		return false
+	}
+
+	if v, ok := cc["max-age"]; ok {
+		if age, err := strconv.Atoi(v); err == nil {
+			return time.Since(e.Stored) < time.Duration(age)*time.Second
+		}
+	}
+
+	if exp := e.Header.Get("Expires"); exp != "" {
+		if t, err := http.ParseTime(exp); err == nil {
+			return time.Now().Before(t)
+		}
+	}
+
+	return false
+}
+
+func (e *CacheEntry) Response(req *http.Request, body io.ReadCloser) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(e.Status) + " " + http.StatusText(e.Status),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          body,
+		ContentLength: e.Size,
+		Request:       req,
+	}
+}
+
+func ParseCacheControl(header string) map[string]string {
+	res := make(map[string]string)
+	for _, dir := range strings.Split(header, ",") {
+		dir = strings.TrimSpace(strings.ToLower(dir))
+		if dir == "" {
+			continue
+		}
+
+		k, v, _ := strings.Cut(dir, "=")
+		res[k] = strings.Trim(v, "\"")
+	}
+
+	return res
+}
+
+func (t *CachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		if Offline {
+			return nil, fmt.Errorf("%w: %s %s", ErrOffline, req.Method, req.URL)
+		}
+
+		return t.Transport.RoundTrip(req)
+	}
+
+	url := req.URL.String()
+	entry, cached := t.Cache.Load(url)
+
+	if Offline {
+		if !cached {
+			return nil, fmt.Errorf("%w: %s is not cached", ErrOffline, url)
+		}
+
+		return t.serve(req, entry)
+	}
+
+	if cached && entry.Fresh() {
+		if res, err := t.serve(req, entry); err == nil {
+			return res, nil
+		}
+
+		cached = false
+	}
+
+	upstream := req
+	if cached {
+		upstream = req.Clone(req.Context())
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			upstream.Header.Set("If-None-Match", etag)
+		}
+
+		if mod := entry.Header.Get("Last-Modified"); mod != "" {
+			upstream.Header.Set("If-Modified-Since", mod)
+		}
+	}
+
+	res, err := t.Transport.RoundTrip(upstream)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached && res.StatusCode == http.StatusNotModified {
+		res.Body.Close()
+
+		for _, h := range []string{"Cache-Control", "Expires", "ETag", "Last-Modified", "Date"} {
+			if v := res.Header.Get(h); v != "" {
+				entry.Header.Set(h, v)
+			}
+		}
+
+		entry.Stored = time.Now()
+		t.Cache.Store(entry, nil)
+
+		return t.serve(req, entry)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return res, nil
+	}
+
+	if _, ok := ParseCacheControl(res.Header.Get("Cache-Control"))["no-store"]; ok {
+		return res, nil
+	}
+
+	return t.store(req, res)
+}
+
+func (t *CachingTransport) serve(req *http.Request, entry *CacheEntry) (*http.Response, error) {
+	body, err := t.Cache.Body(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return entry.Response(req, body), nil
+}
+
+// store saves the response body if it's small enough, responses bigger
+// than an eighth of the cache are passed through without being cached
+func (t *CachingTransport) store(req *http.Request, res *http.Response) (*http.Response, error) {
+	limit := t.Cache.MaxSize / 8
+	if limit > 0 && res.ContentLength > limit {
+		return res, nil
+	}
+
+	var buf bytes.Buffer
+	reader := io.Reader(res.Body)
+	if limit > 0 {
+		reader = io.LimitReader(res.Body, limit+1)
+	}
+
+	if _, err := io.Copy(&buf, reader); err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+
+	if limit > 0 && int64(buf.Len()) > limit {
+		res.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(&buf, res.Body), res.Body}
+
+		return res, nil
+	}
+
+	res.Body.Close()
+
+	entry := &CacheEntry{
+		URL:    req.URL.String(),
+		Status: res.StatusCode,
+		Header: res.Header.Clone(),
+		Stored: time.Now(),
+	}
+
+	if err := t.Cache.Store(entry, buf.Bytes()); err != nil && DEBUG {
+		log.Printf("http cache: failed to store %s: %v\n", entry.URL, err)
+	}
+
+	res.Body = io.NopCloser(bytes.NewReader(buf.Bytes()))
+	res.ContentLength = int64(buf.Len())
+
+	return res, nil
+}
@@ -0,0 +1,120 @@
+package bucket
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCachingTransportRevalidation(t *testing.T) {
+	requests, revalidated := 0, 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if r.Header.Get("If-None-Match") == "\"v1\"" {
+			revalidated++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", "\"v1\"")
+		w.Write([]byte("payload"))
+	}))
+
+	defer srv.Close()
+
+	client := &http.Client{Transport: &CachingTransport{
+		Transport: http.DefaultTransport,
+		Cache:     NewHTTPCache(t.TempDir(), 1<<20),
+	}}
+
+	for i := 0; i < 3; i++ {
+		res, err := client.Get(srv.URL + "/project")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if string(body) != "payload" {
+			t.Fatalf("request %d: unexpected body %q", i, body)
+		}
+	}
+
+	if requests != 3 || revalidated != 2 {
+		t.Fatalf("expected 3 requests with 2 revalidations, got %d and %d", requests, revalidated)
+	}
+}
+
+func TestCachingTransportMaxAge(t *testing.T) {
+	requests := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Cache-Control", "public, max-age=600")
+		w.Write([]byte("fresh"))
+	}))
+
+	defer srv.Close()
+
+	client := &http.Client{Transport: &CachingTransport{
+		Transport: http.DefaultTransport,
+		Cache:     NewHTTPCache(t.TempDir(), 1<<20),
+	}}
+
+	for i := 0; i < 2; i++ {
+		res, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res.Body.Close()
+	}
+
+	if requests != 1 {
+		t.Fatalf("expected fresh entry to be served from cache, got %d requests", requests)
+	}
+}
+
+func TestCachingTransportOffline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("cached"))
+	}))
+
+	defer srv.Close()
+	defer func() { Offline = false }()
+
+	client := &http.Client{Transport: &CachingTransport{
+		Transport: http.DefaultTransport,
+		Cache:     NewHTTPCache(t.TempDir(), 1<<20),
+	}}
+
+	res, err := client.Get(srv.URL + "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	Offline = true
+
+	res, err = client.Get(srv.URL + "/a")
+	if err != nil {
+		t.Fatalf("cached response should be served offline: %v", err)
+	}
+
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+
+	if string(body) != "cached" {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	if _, err := client.Get(srv.URL + "/b"); !errors.Is(err, ErrOffline) {
+		t.Fatalf("expected offline error, got %v", err)
+	}
+}
@@ -1,14 +1,221 @@
 package bucket
 
-func (c *OpenContext) InstallLatest(plugin RemotePlugin) error {
-	latest, err := plugin.GetLatestVersion()
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/MRtecno98/afero"
+)
+
+// Suffix of files still being downloaded
+const StagingSuffix = ".part"
+
+// Returned by platforms without a download API
+var ErrInstallUnsupported = errors.New("automatic installation not supported")
+
+// Clients of the download APIs, replaceable in tests. Jars skip the HTTP
+// cache so only the JSON responses of the APIs are stored
+var (
+	APIClient      = NewHTTPClient
+	DownloadClient = func() *http.Client { return &http.Client{Transport: SharedRetryTransport()} }
+)
+
+// ServerDownload is a server jar published by a platform's download API,
+// checked against the published checksum once downloaded
+type ServerDownload struct {
+	URL      string
+	FileName string
+
+	// Hex checksum computed with NewHash, skipped if empty
+	Checksum string
+	NewHash  func() hash.Hash
+
+	hasher hash.Hash
+}
+
+func (d *ServerDownload) Name() string {
+	return d.FileName
+}
+
+func (d *ServerDownload) Optional() bool {
+	return false
+}
+
+func (d *ServerDownload) Download(ctx context.Context) (io.ReadCloser, error) {
+	res, err := requestURL(ctx, DownloadClient(), d.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Checksum == "" {
+		return res.Body, nil
+	}
+
+	d.hasher = d.NewHash()
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(res.Body, d.hasher), res.Body}, nil
+}
+
+func (d *ServerDownload) Verify() error {
+	if d.Checksum == "" {
+		return nil
+	}
+
+	if d.hasher == nil {
+		return errors.New("file not downloaded")
+	}
+
+	if sum := hex.EncodeToString(d.hasher.Sum(nil)); sum != d.Checksum {
+		return fmt.Errorf("%s: checksum mismatch, expected %s got %s", d.FileName, d.Checksum, sum)
+	}
+
+	return nil
+}
+
+// InstallServer downloads the server jar in the context root
+func (c *OpenContext) InstallServer(download *ServerDownload) error {
+	return DownloadFile(c.Lock, c.Fs, download)
+}
+
+// FetchJSON decodes the JSON response of a download API
+func FetchJSON(ctx context.Context, url string, out any) error {
+	res, err := requestURL(ctx, APIClient(), url)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+func requestURL(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", UserAgent)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("%s: unexpected status %s", url, res.Status)
+	}
+
+	return res, nil
+}
+
+func (c *OpenContext) InstallLatest(ctx context.Context, plugin RemotePlugin) error {
+	latest, err := plugin.GetLatestVersion(ctx)
 	if err != nil {
 		return err
 	}
 
-	return c.InstallVersion(latest)
+	return c.InstallVersion(ctx, latest)
 }
 
-func (c *OpenContext) InstallVersion(ver RemoteVersion) error {
+func (c *OpenContext) InstallVersion(ctx context.Context, ver RemoteVersion) error {
+	files, err := ver.GetFiles(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := c.Fs.MkdirAll(c.Platform.PluginsFolder(), 0755); err != nil {
+		return err
+	}
+
+	folder := afero.NewBasePathFs(c.Fs, c.Platform.PluginsFolder())
+	for _, f := range files {
+		if f.Optional() {
+			continue
+		}
+
+		if err := DownloadFile(ctx, folder, f); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// DownloadFile writes the remote file in the filesystem through a staging
+// file, which is renamed only once the download is complete and verified.
+// Failed or cancelled downloads leave no file behind
+func DownloadFile(ctx context.Context, fs afero.Fs, f RemoteFile) (err error) {
+	data, err := f.Download(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer data.Close()
+
+	part := f.Name() + StagingSuffix
+	fd, err := fs.OpenFile(part, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	closed := false
+	defer func() {
+		if err != nil {
+			if !closed {
+				fd.Close()
+			}
+
+			fs.Remove(part)
+		}
+	}()
+
+	if _, err = io.Copy(fd, data); err != nil {
+		return err
+	}
+
+	// The body might have been cut short without an error
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	closed = true
+	if err = fd.Close(); err != nil {
+		return err
+	}
+
+	if err = f.Verify(); err != nil {
+		return err
+	}
+
+	return renameOver(fs, part, f.Name())
+}
+
+// renameOver replaces newname with oldname. SFTP renames don't overwrite
+// existing files, so the target is removed first when the rename fails
+func renameOver(fs afero.Fs, oldname, newname string) error {
+	err := fs.Rename(oldname, newname)
+	if err == nil {
+		return nil
+	}
+
+	if _, serr := fs.Stat(newname); serr != nil {
+		return err
+	}
+
+	if err := fs.Remove(newname); err != nil {
+		return err
+	}
+
+	return fs.Rename(oldname, newname)
+}
@@ -0,0 +1,94 @@
+package bucket
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/MRtecno98/afero"
+)
+
+type testFile struct {
+	data string
+	fail error
+}
+
+func (f *testFile) Name() string   { return "plugin.jar" }
+func (f *testFile) Optional() bool { return false }
+func (f *testFile) Verify() error  { return nil }
+
+func (f *testFile) Download(ctx context.Context) (io.ReadCloser, error) {
+	var r io.Reader = strings.NewReader(f.data)
+	if f.fail != nil {
+		r = io.MultiReader(r, &failingReader{f.fail})
+	}
+
+	return io.NopCloser(r), nil
+}
+
+type failingReader struct{ err error }
+
+func (r *failingReader) Read([]byte) (int, error) { return 0, r.err }
+
+func TestDownloadFile(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+
+	if err := DownloadFile(context.Background(), fs, &testFile{data: "jar"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := fs.ReadFile("plugin.jar"); err != nil || string(data) != "jar" {
+		t.Fatalf("unexpected file content %q: %v", data, err)
+	}
+
+	if ok, _ := fs.Exists("plugin.jar" + StagingSuffix); ok {
+		t.Fatal("staging file left behind")
+	}
+}
+
+// noOverwriteFs renames like SFTP servers, failing on existing targets
+type noOverwriteFs struct {
+	afero.Fs
+}
+
+func (fs noOverwriteFs) Rename(oldname, newname string) error {
+	if _, err := fs.Stat(newname); err == nil {
+		return os.ErrExist
+	}
+
+	return fs.Fs.Rename(oldname, newname)
+}
+
+func TestDownloadFileReplace(t *testing.T) {
+	fs := afero.Afero{Fs: noOverwriteFs{afero.NewMemMapFs()}}
+	fs.WriteFile("plugin.jar", []byte("old"), 0644)
+
+	if err := DownloadFile(context.Background(), fs, &testFile{data: "new"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := fs.ReadFile("plugin.jar"); string(data) != "new" {
+		t.Fatalf("existing jar not replaced: %q", data)
+	}
+}
+
+func TestDownloadFileCleanup(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := DownloadFile(ctx, fs, &testFile{data: "partial", fail: context.Canceled})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+
+	for _, name := range []string{"plugin.jar", "plugin.jar" + StagingSuffix} {
+		if ok, _ := fs.Exists(name); ok {
+			t.Fatalf("%s left behind after a failed download", name)
+		}
+	}
+}
@@ -1,8 +1,14 @@
 package bucket
 
-import "cmp"
+import (
+	"cmp"
+	"sync"
+)
 
+// BiMap is a map indexed by two keys, it's safe for concurrent use
 type BiMap[K1 cmp.Ordered, K2 cmp.Ordered, V any] struct {
+	lock sync.RWMutex
+
 	first  map[K1]V
 	second map[K2]V
 
@@ -19,22 +25,34 @@ func NewBiMap[K1 cmp.Ordered, K2 cmp.Ordered, V any](
 }
 
 func (bm *BiMap[K1, K2, V]) Put(value V) {
+	bm.lock.Lock()
+	defer bm.lock.Unlock()
+
 	first, second := bm.keyfunc(value)
 	bm.first[first] = value
 	bm.second[second] = value
 }
 
 func (bm *BiMap[K1, K2, V]) GetFirst(key K1) (V, bool) {
+	bm.lock.RLock()
+	defer bm.lock.RUnlock()
+
 	value, ok := bm.first[key]
 	return value, ok
 }
 
 func (bm *BiMap[K1, K2, V]) GetSecond(key K2) (V, bool) {
+	bm.lock.RLock()
+	defer bm.lock.RUnlock()
+
 	value, ok := bm.second[key]
 	return value, ok
 }
 
 func (bm *BiMap[K1, K2, V]) DeleteFirst(key K1) {
+	bm.lock.Lock()
+	defer bm.lock.Unlock()
+
 	value, ok := bm.first[key]
 	if ok {
 		delete(bm.first, key)
@@ -44,6 +62,9 @@ func (bm *BiMap[K1, K2, V]) DeleteFirst(key K1) {
 }
 
 func (bm *BiMap[K1, K2, V]) DeleteSecond(key K2) {
+	bm.lock.Lock()
+	defer bm.lock.Unlock()
+
 	value, ok := bm.second[key]
 	if ok {
 		delete(bm.second, key)
@@ -53,6 +74,9 @@ func (bm *BiMap[K1, K2, V]) DeleteSecond(key K2) {
 }
 
 func (bm *BiMap[K1, K2, V]) Values() []V {
+	bm.lock.RLock()
+	defer bm.lock.RUnlock()
+
 	var values []V
 	for _, v := range bm.first {
 		values = append(values, v)
@@ -60,6 +84,13 @@ func (bm *BiMap[K1, K2, V]) Values() []V {
 	return values
 }
 
+func (bm *BiMap[K1, K2, V]) Len() int {
+	bm.lock.RLock()
+	defer bm.lock.RUnlock()
+
+	return len(bm.first)
+}
+
 type SymmetricBiMap[K cmp.Ordered, V any] struct {
 	BiMap[K, K, V]
 }
@@ -76,12 +107,15 @@ func NewSymmetricBiMap[K cmp.Ordered, V any](
 }
 
 func (sbm *SymmetricBiMap[K, V]) GetAny(key K) (V, bool) {
-	value, ok := sbm.GetFirst(key)
-	if ok {
+	sbm.lock.RLock()
+	defer sbm.lock.RUnlock()
+
+	if value, ok := sbm.first[key]; ok {
 		return value, ok
 	}
 
-	return sbm.GetSecond(key)
+	value, ok := sbm.second[key]
+	return value, ok
 }
 
 func (sbm *SymmetricBiMap[K, V]) Delete(key K) {
@@ -90,8 +124,11 @@ func (sbm *SymmetricBiMap[K, V]) Delete(key K) {
 }
 
 func (sbm *SymmetricBiMap[K, V]) GetStrict(key K) (V, bool) {
-	v, a := sbm.GetFirst(key)
-	_, b := sbm.GetSecond(key)
+	sbm.lock.RLock()
+	defer sbm.lock.RUnlock()
+
+	v, a := sbm.first[key]
+	_, b := sbm.second[key]
 
 	return v, a && b
 }
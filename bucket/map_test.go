@@ -0,0 +1,44 @@
+package bucket
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestSymmetricBiMapConcurrent(t *testing.T) {
+	bm := NewSymmetricBiMap(func(el int) (string, string) {
+		return "l" + strconv.Itoa(el), "r" + strconv.Itoa(el)
+	})
+
+	var wait sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wait.Add(1)
+
+		go func(i int) {
+			defer wait.Done()
+
+			bm.Put(i)
+			bm.GetAny("r" + strconv.Itoa(i))
+			bm.Values()
+
+			if i%2 == 0 {
+				bm.Delete("l" + strconv.Itoa(i))
+			}
+		}(i)
+	}
+
+	wait.Wait()
+
+	if bm.Len() != 32 {
+		t.Fatalf("expected 32 elements, got %d", bm.Len())
+	}
+
+	if _, ok := bm.GetStrict("l1"); ok {
+		t.Fatalf("strict lookup should require both keys")
+	}
+
+	if v, ok := bm.GetAny("r3"); !ok || v != 3 {
+		t.Fatalf("expected 3, got %d", v)
+	}
+}
@@ -0,0 +1,219 @@
+package bucket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/MRtecno98/afero"
+	"github.com/hashicorp/go-multierror"
+)
+
+// MirrorFilter selects the plugins to mirror by name, with glob patterns.
+// Without includes every plugin is selected, excludes win over includes
+type MirrorFilter struct {
+	Include []string
+	Exclude []string
+}
+
+func (f MirrorFilter) Match(name string) bool {
+	matches := func(patterns []string) bool {
+		for _, p := range patterns {
+			if ok, _ := path.Match(strings.ToLower(p), strings.ToLower(name)); ok {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return (len(f.Include) == 0 || matches(f.Include)) && !matches(f.Exclude)
+}
+
+// MirrorInstall is a plugin to download in the target, replacing the
+// installed jar of another version if any
+type MirrorInstall struct {
+	LockEntry
+	Replaces *PluginState
+}
+
+// MirrorPlan lists the changes that reproduce the plugins of a source on a target.
+// Resolved plugins are installed through the target repositories, unresolved
+// ones are copied as jars and plugins missing in the source are removed
+type MirrorPlan struct {
+	Install []MirrorInstall
+	Copy    []*LocalPlugin
+	Remove  []PluginState
+
+	// Source plugins that failed to load, no plugin is removed
+	// when there are any since they'd look missing from the source
+	SourceErrors []error
+}
+
+func (p *MirrorPlan) Empty() bool {
+	return len(p.Install) == 0 && len(p.Copy) == 0 && len(p.Remove) == 0
+}
+
+// PlanMirror compares the plugins of the target with the source ones
+func (c *OpenContext) PlanMirror(ctx context.Context, source *OpenContext, filter MirrorFilter) (*MirrorPlan, error) {
+	if source.Platform == nil {
+		return nil, errors.New("no platform detected in " + source.Name)
+	}
+
+	plugins, perrs, err := source.Platform.Plugins()
+	if err != nil {
+		if len(plugins) == 0 {
+			return nil, err
+		}
+
+		perrs = append(perrs, err)
+	}
+
+	lock, unresolved, err := source.lockPlugins(ctx, plugins)
+	if err != nil {
+		return nil, err
+	}
+
+	states, err := c.PluginStates()
+	if err != nil {
+		return nil, err
+	}
+
+	// Target plugins are matched by remote identifier, falling back to the name
+	find := func(key, name string) (PluginState, bool) {
+		state, ok := states[key]
+		if !ok {
+			key = "name:" + strings.ToLower(name)
+			state, ok = states[key]
+		}
+
+		if ok {
+			delete(states, key)
+		}
+
+		return state, ok
+	}
+
+	plan := &MirrorPlan{SourceErrors: perrs}
+	for _, entry := range lock.Plugins {
+		if !filter.Match(entry.Name) {
+			continue
+		}
+
+		state, ok := find(entry.Repository+":"+entry.Identifier, entry.Name)
+		if ok && (state.Sha256 == entry.Sha256 || sameVersion(state.Version, entry.Version)) {
+			continue
+		}
+
+		install := MirrorInstall{LockEntry: entry}
+		if ok {
+			install.Replaces = &state
+		}
+
+		plan.Install = append(plan.Install, install)
+	}
+
+	for _, pl := range unresolved {
+		if !filter.Match(pl.GetName()) {
+			continue
+		}
+
+		hash, err := pl.Hash()
+		if err != nil {
+			return nil, err
+		}
+
+		if state, ok := find("", pl.GetName()); ok && state.Sha256 == hash {
+			continue
+		}
+
+		plan.Copy = append(plan.Copy, pl)
+	}
+
+	// Plugins that failed to load in the source would be deleted everywhere
+	if len(perrs) > 0 {
+		return plan, nil
+	}
+
+	for _, state := range states {
+		if filter.Match(state.Name) && state.File != "" {
+			plan.Remove = append(plan.Remove, state)
+		}
+	}
+
+	slices.SortFunc(plan.Remove, func(a, b PluginState) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+
+	return plan, nil
+}
+
+// ApplyMirror runs the plan on the target, the replaced jars are removed only
+// once the new version is installed so a failed download keeps the old one
+func (c *OpenContext) ApplyMirror(ctx context.Context, plan *MirrorPlan) error {
+	if err := c.Fs.MkdirAll(c.Platform.PluginsFolder(), 0755); err != nil {
+		return err
+	}
+
+	folder := afero.Afero{Fs: afero.NewBasePathFs(c.Fs, c.Platform.PluginsFolder())}
+
+	var errs *multierror.Error
+	for _, install := range plan.Install {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := c.installLocked(ctx, folder, install.LockEntry); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("%s: %w", install.Name, err))
+			continue
+		}
+
+		// Jars with the same name were overwritten by the download
+		if old := install.Replaces; old != nil && old.File != "" {
+			if hash, err := hashPath(folder, old.File); err == nil && hash == old.Sha256 {
+				if err := folder.Remove(old.File); err != nil {
+					errs = multierror.Append(errs, err)
+				}
+			}
+		}
+	}
+
+	for _, pl := range plan.Copy {
+		if err := copyPlugin(folder, pl); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("%s: %w", pl.GetName(), err))
+		}
+	}
+
+	for _, state := range plan.Remove {
+		if err := folder.Remove(state.File); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("%s: %w", state.Name, err))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+func copyPlugin(folder afero.Afero, pl *LocalPlugin) error {
+	if _, err := pl.File.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	dst, err := folder.OpenFile(path.Base(filepath.ToSlash(pl.File.Name())),
+		os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, pl.File); err != nil {
+		dst.Close()
+		return err
+	}
+
+	return dst.Close()
+}
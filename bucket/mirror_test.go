@@ -0,0 +1,92 @@
+package bucket
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MRtecno98/afero"
+)
+
+func TestMirror(t *testing.T) {
+	open := func(name string) *OpenContext {
+		return &OpenContext{Context: Context{Name: name}, Fs: afero.Afero{Fs: afero.NewMemMapFs()},
+			Platform: &pluginsPlatform{}, PluginDatabase: NewSqliteDatabase(), LocalConfig: &Config{}}
+	}
+
+	jar := func(fs afero.Afero, name, data string) afero.File {
+		fs.MkdirAll("plugins", 0755)
+		fs.WriteFile("plugins/"+name, []byte(data), 0644)
+		f, _ := fs.Open("plugins/" + name)
+		return f
+	}
+
+	source, target := open("lobby-1"), open("lobby-2")
+
+	source.Plugins().Put(CachedPlugin{CachedRecord: CachedRecord{LocalIdentifier: "essentials", RemoteIdentifier: "hXiIvTyT"},
+		Repository: NamedRepository{Repository: &fakeRepository{}, RepositoryConfig: RepositoryConfig{Name: "modrinth"}}})
+
+	source.Platform.(*pluginsPlatform).plugins = []Plugin{
+		&LocalPlugin{PluginDescriptor: versionedDescriptor{testDescriptor{"Essentials"}, "2.21.0"},
+			File: jar(source.Fs, "Essentials-2.21.0.jar", "new")},
+		&LocalPlugin{PluginDescriptor: testDescriptor{"Custom"}, File: jar(source.Fs, "Custom.jar", "custom")},
+		&LocalPlugin{PluginDescriptor: testDescriptor{"Same"}, File: jar(source.Fs, "Same.jar", "same")},
+	}
+
+	target.Platform.(*pluginsPlatform).plugins = []Plugin{
+		&LocalPlugin{PluginDescriptor: versionedDescriptor{testDescriptor{"Essentials"}, "2.20.0"},
+			File: jar(target.Fs, "Essentials-2.20.0.jar", "old")},
+		&LocalPlugin{PluginDescriptor: testDescriptor{"Same"}, File: jar(target.Fs, "Same.jar", "same")},
+		&LocalPlugin{PluginDescriptor: testDescriptor{"Extra"}, File: jar(target.Fs, "Extra.jar", "extra")},
+		&LocalPlugin{PluginDescriptor: testDescriptor{"Dynmap"}, File: jar(target.Fs, "Dynmap.jar", "map")},
+	}
+
+	plan, err := target.PlanMirror(context.Background(), source, MirrorFilter{Exclude: []string{"dyn*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Install) != 1 || plan.Install[0].Identifier != "hXiIvTyT" ||
+		plan.Install[0].Replaces == nil || plan.Install[0].Replaces.File != "Essentials-2.20.0.jar" {
+		t.Fatalf("wrong installs: %+v", plan.Install)
+	}
+
+	if len(plan.Copy) != 1 || plan.Copy[0].GetName() != "Custom" {
+		t.Fatalf("wrong copies: %+v", plan.Copy)
+	}
+
+	// Excluded plugins are left alone on the target
+	if len(plan.Remove) != 1 || plan.Remove[0].File != "Extra.jar" {
+		t.Fatalf("wrong removals: %+v", plan.Remove)
+	}
+
+	plan.Install = nil
+	if err := target.ApplyMirror(context.Background(), plan); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := target.Fs.ReadFile("plugins/Custom.jar"); string(data) != "custom" {
+		t.Fatal("unresolved jar not copied")
+	}
+
+	if ok, _ := target.Fs.Exists("plugins/Extra.jar"); ok {
+		t.Fatal("extra plugin not removed")
+	}
+
+	if ok, _ := target.Fs.Exists("plugins/Dynmap.jar"); !ok {
+		t.Fatal("excluded plugin removed")
+	}
+
+	// Plugins failing to load in the source must not be removed from the targets
+	target.Fs.WriteFile("plugins/Extra.jar", []byte("extra"), 0644)
+	source.Platform.(*pluginsPlatform).errs = []error{errors.New("Extra.jar: invalid plugin.yml")}
+
+	plan, err = target.PlanMirror(context.Background(), source, MirrorFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.SourceErrors) != 1 || len(plan.Remove) != 0 {
+		t.Fatalf("removals planned despite source errors: %+v", plan)
+	}
+}
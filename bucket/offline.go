@@ -0,0 +1,20 @@
+package bucket
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Offline makes every repository work from cached data only
+var Offline = false
+
+var ErrOffline = errors.New("offline mode")
+
+// RequireOnline fails if the action needs network access and offline mode is enabled
+func RequireOnline(action string) error {
+	if Offline {
+		return fmt.Errorf("%w: %s requires network access", ErrOffline, action)
+	}
+
+	return nil
+}
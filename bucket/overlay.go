@@ -0,0 +1,462 @@
+package bucket
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/MRtecno98/afero"
+	"github.com/MRtecno98/afero/resolver"
+	"gopkg.in/yaml.v2"
+)
+
+// Hashes of the files written by the last apply, to find the ones edited since
+const OverlayStateFile = ".bucket/overlays.json"
+
+type DriftStatus string
+
+const (
+	// Edited on the server after the overlay was applied
+	DriftModified DriftStatus = "modified"
+	DriftMissing  DriftStatus = "missing"
+
+	// The overlay renders something else than what was applied
+	DriftOutdated DriftStatus = "outdated"
+)
+
+// ${NAME} references a variable and ${secret:NAME} a secret
+var overlayVariable = regexp.MustCompile(`\$\{(secret:)?([A-Za-z0-9_.-]+)\}`)
+
+type RenderedFile struct {
+	Path string
+	Data []byte
+}
+
+type OverlayDrift struct {
+	Path   string
+	Status DriftStatus
+}
+
+type OverlayResult struct {
+	Written   []string
+	Unchanged int
+
+	// Edited by hand and left alone, unless forced
+	Skipped []string
+
+	// Variables without a value, left as they are
+	Unknown []string
+}
+
+// OverlayVariables are the variables of the config and of the context
+// definition, which take precedence, plus CONTEXT and PLATFORM
+func (c *OpenContext) OverlayVariables() map[string]string {
+	vars := map[string]string{"CONTEXT": c.Name, "PLATFORM": c.PlatformName()}
+	maps.Copy(vars, c.Config().Variables)
+	maps.Copy(vars, c.Context.Variables)
+
+	return vars
+}
+
+// Overlays merges the overlays with the same target, the local config
+// comes before the global one and its patches are merged on top
+func (c *OpenContext) Overlays() ([]OverlayConfig, error) {
+	var merged []OverlayConfig
+	index := make(map[string]int)
+
+	for _, o := range slices.Backward(c.Config().Overlays) {
+		target := path.Clean(filepath.ToSlash(o.Target))
+		if !filepath.IsLocal(target) {
+			return nil, fmt.Errorf("overlay target outside of the context: %s", o.Target)
+		}
+
+		i, ok := index[target]
+		if !ok {
+			index[target] = len(merged)
+			merged = append(merged, OverlayConfig{Target: target, Patches: make(map[string]yaml.MapSlice)})
+			i = len(merged) - 1
+		}
+
+		if o.Base != "" {
+			merged[i].Base, merged[i].Folder = o.Base, o.Folder
+		}
+
+		for name, patch := range o.Patches {
+			name = path.Clean(filepath.ToSlash(name))
+			merged[i].Patches[name] = mergeYAML(merged[i].Patches[name], patch, false)
+		}
+	}
+
+	for _, o := range merged {
+		if o.Base == "" {
+			return nil, fmt.Errorf("overlay of %s has no base", o.Target)
+		}
+	}
+
+	slices.SortFunc(merged, func(a, b OverlayConfig) int {
+		return strings.Compare(a.Target, b.Target)
+	})
+
+	return merged, nil
+}
+
+// RenderOverlays renders the overlay files with their context path,
+// returning the variables without a value too
+func (c *OpenContext) RenderOverlays() ([]RenderedFile, []string, error) {
+	overlays, err := c.Overlays()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vars := c.OverlayVariables()
+	unknown := make(map[string]bool)
+
+	var rendered []RenderedFile
+	for _, o := range overlays {
+		files, err := c.renderOverlay(o, vars, unknown)
+		if err != nil {
+			return nil, nil, fmt.Errorf("overlay of %s: %w", o.Target, err)
+		}
+
+		rendered = append(rendered, files...)
+	}
+
+	return rendered, slices.Sorted(maps.Keys(unknown)), nil
+}
+
+func (c *OpenContext) renderOverlay(o OverlayConfig, vars map[string]string, unknown map[string]bool) ([]RenderedFile, error) {
+	fs, err := resolver.OpenUrl(c.Config().FindContext(o.Base).URL)
+	if err != nil {
+		return nil, err
+	}
+
+	defer fs.Close()
+
+	// Base files by their path in the folder
+	base := make(map[string][]byte)
+	root := path.Clean(filepath.ToSlash(o.Folder))
+	if err := (&afero.Afero{Fs: fs}).Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+
+		base[filepath.ToSlash(rel)], err = afero.ReadFile(fs, name)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	// Patches without a base file make a new one
+	for name := range o.Patches {
+		if _, ok := base[name]; !ok {
+			base[name] = nil
+		}
+	}
+
+	var files []RenderedFile
+	for _, name := range slices.Sorted(maps.Keys(base)) {
+		data := base[name]
+		ext := strings.ToLower(path.Ext(name))
+
+		isYAML := ext == ".yml" || ext == ".yaml"
+		patch, patched := o.Patches[name]
+		if patched && !isYAML {
+			return nil, fmt.Errorf("%s: only YAML files can be patched", name)
+		}
+
+		// Variables in YAML files are replaced inside the parsed values so
+		// that quotes or colons in them can't break the file. Comments of
+		// the base file don't survive it, like with the merge
+		if patched || isYAML && overlayVariable.Match(data) {
+			var doc yaml.MapSlice
+			if err := yaml.Unmarshal(data, &doc); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+
+			rendered, err := c.substituteYAML(mergeYAML(doc, patch, true), vars, unknown)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+
+			if data, err = yaml.Marshal(rendered); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		} else if slices.Contains(BackupConfigExts, ext) {
+			if data, err = c.substituteVariables(data, vars, unknown); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+
+		files = append(files, RenderedFile{Path: path.Join(o.Target, name), Data: data})
+	}
+
+	return files, nil
+}
+
+// substituteVariables replaces the variables and the secrets, unknown
+// variables are left as they are but secrets must all be found
+func (c *OpenContext) substituteVariables(data []byte, vars map[string]string, unknown map[string]bool) ([]byte, error) {
+	var failed error
+	data = overlayVariable.ReplaceAllFunc(data, func(match []byte) []byte {
+		groups := overlayVariable.FindSubmatch(match)
+		name := string(groups[2])
+
+		if len(groups[1]) > 0 {
+			secret, err := c.Secret(c.Lock, name)
+			if err != nil {
+				failed = cmp.Or(failed, err)
+				return match
+			}
+
+			return []byte(secret)
+		}
+
+		if v, ok := vars[name]; ok {
+			return []byte(v)
+		}
+
+		unknown[name] = true
+		return match
+	})
+
+	return data, failed
+}
+
+// substituteYAML replaces the variables and the secrets in the string values
+// of a YAML document. A value made of a single variable takes the type of
+// the variable value, so numbers and booleans stay as they are
+func (c *OpenContext) substituteYAML(value interface{}, vars map[string]string, unknown map[string]bool) (interface{}, error) {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		out := make(yaml.MapSlice, len(v))
+		for i, item := range v {
+			rendered, err := c.substituteYAML(item.Value, vars, unknown)
+			if err != nil {
+				return nil, err
+			}
+
+			out[i] = yaml.MapItem{Key: item.Key, Value: rendered}
+		}
+
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			rendered, err := c.substituteYAML(item, vars, unknown)
+			if err != nil {
+				return nil, err
+			}
+
+			out[i] = rendered
+		}
+
+		return out, nil
+	case string:
+		rendered, err := c.substituteVariables([]byte(v), vars, unknown)
+		if err != nil {
+			return nil, err
+		}
+
+		if m := overlayVariable.FindStringSubmatch(v); m != nil && m[0] == v && m[1] == "" {
+			var typed interface{}
+			if yaml.Unmarshal(rendered, &typed) == nil {
+				switch typed.(type) {
+				case bool, int, int64, uint64, float64:
+					return typed, nil
+				}
+			}
+		}
+
+		return string(rendered), nil
+	}
+
+	return value, nil
+}
+
+// LoadSecretMasks renders the overlays to learn the values of their secrets,
+// so that they're masked in the output. Failures are ignored
+func (c *OpenContext) LoadSecretMasks() {
+	if len(c.Config().Overlays) > 0 {
+		c.RenderOverlays()
+	}
+}
+
+// mergeYAML deep merges the patch in the base mapping: patch values replace
+// base ones unless both are mappings, and null values delete the key.
+// Patches merged together keep their nulls for the base instead
+func mergeYAML(base, patch yaml.MapSlice, deleteNull bool) yaml.MapSlice {
+	merged := slices.Clone(base)
+	for _, item := range patch {
+		i := slices.IndexFunc(merged, func(m yaml.MapItem) bool {
+			return fmt.Sprint(m.Key) == fmt.Sprint(item.Key)
+		})
+
+		switch {
+		case item.Value == nil && deleteNull:
+			if i >= 0 {
+				merged = slices.Delete(merged, i, i+1)
+			}
+		case i < 0:
+			merged = append(merged, item)
+		default:
+			pm, pok := item.Value.(yaml.MapSlice)
+			bm, bok := merged[i].Value.(yaml.MapSlice)
+
+			if pok && bok {
+				merged[i].Value = mergeYAML(bm, pm, deleteNull)
+			} else {
+				merged[i].Value = item.Value
+			}
+		}
+	}
+
+	return merged
+}
+
+func (c *OpenContext) overlayState() (map[string]string, error) {
+	state := make(map[string]string)
+
+	data, err := c.Fs.ReadFile(OverlayStateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("%s: %w", OverlayStateFile, err)
+	}
+
+	return state, nil
+}
+
+func (c *OpenContext) saveOverlayState(state map[string]string) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := c.Fs.MkdirAll(path.Dir(OverlayStateFile), 0755); err != nil {
+		return err
+	}
+
+	return c.Fs.WriteFile(OverlayStateFile, data, 0644)
+}
+
+// fileSum hashes a context file, empty if it doesn't exist
+func (c *OpenContext) fileSum(name string) (string, error) {
+	data, err := c.Fs.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	return dataSum(data), nil
+}
+
+func dataSum(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// ApplyOverlays writes the rendered overlays in the context. Files edited
+// by hand since the last apply are skipped, unless forced
+func (c *OpenContext) ApplyOverlays(force bool) (*OverlayResult, error) {
+	rendered, unknown, err := c.RenderOverlays()
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := c.overlayState()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OverlayResult{Unknown: unknown}
+	applied := make(map[string]string, len(rendered))
+
+	for _, f := range rendered {
+		current, err := c.fileSum(f.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		sum := dataSum(f.Data)
+		if last, ok := state[f.Path]; ok && current != "" && current != last && current != sum && !force {
+			result.Skipped = append(result.Skipped, f.Path)
+			applied[f.Path] = last
+			continue
+		}
+
+		applied[f.Path] = sum
+		if current == sum {
+			result.Unchanged++
+			continue
+		}
+
+		if err := c.Fs.MkdirAll(path.Dir(f.Path), 0755); err != nil {
+			return nil, err
+		}
+
+		if err := c.Fs.WriteFile(f.Path, f.Data, 0644); err != nil {
+			return nil, err
+		}
+
+		result.Written = append(result.Written, f.Path)
+	}
+
+	return result, c.saveOverlayState(applied)
+}
+
+// OverlayDrift compares the context with the last applied overlays
+func (c *OpenContext) OverlayDrift() ([]OverlayDrift, error) {
+	rendered, _, err := c.RenderOverlays()
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := c.overlayState()
+	if err != nil {
+		return nil, err
+	}
+
+	var drift []OverlayDrift
+	for _, name := range slices.Sorted(maps.Keys(state)) {
+		current, err := c.fileSum(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if current == "" {
+			drift = append(drift, OverlayDrift{name, DriftMissing})
+		} else if current != state[name] {
+			drift = append(drift, OverlayDrift{name, DriftModified})
+		}
+	}
+
+	for _, f := range rendered {
+		if sum, ok := state[f.Path]; !ok || sum != dataSum(f.Data) {
+			if !slices.ContainsFunc(drift, func(d OverlayDrift) bool { return d.Path == f.Path }) {
+				drift = append(drift, OverlayDrift{f.Path, DriftOutdated})
+			}
+		}
+	}
+
+	return drift, nil
+}
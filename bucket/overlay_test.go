@@ -0,0 +1,101 @@
+package bucket
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MRtecno98/afero"
+	"gopkg.in/yaml.v2"
+)
+
+func TestOverlays(t *testing.T) {
+	base := t.TempDir()
+	os.WriteFile(filepath.Join(base, "config.yml"), []byte(
+		"server: global\nstorage:\n  method: h2\n  pool: 10\nlog-notify: true\n"), 0644)
+	os.WriteFile(filepath.Join(base, "motd.txt"), []byte("Welcome to ${SERVER}\n"), 0644)
+
+	var patch yaml.MapSlice
+	yaml.Unmarshal([]byte("server: ${CONTEXT}\nstorage:\n  method: mysql\nlog-notify: ~\n"), &patch)
+
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	c := &OpenContext{Context: Context{Name: "lobby", Variables: map[string]string{"SERVER": "Lobby"}},
+		Fs: fs, LocalConfig: &Config{Overlays: []OverlayConfig{
+			// Local patches, then the shared overlay from the global config
+			{Target: "plugins/LuckPerms", Patches: map[string]yaml.MapSlice{"config.yml": patch}},
+			{Target: "plugins/LuckPerms/", Base: base},
+		}}}
+
+	result, err := c.ApplyOverlays(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Written) != 2 {
+		t.Fatalf("expected 2 files written, got %v", result.Written)
+	}
+
+	config, _ := fs.ReadFile("plugins/LuckPerms/config.yml")
+	if string(config) != "server: lobby\nstorage:\n  method: mysql\n  pool: 10\n" {
+		t.Fatalf("wrong merged config:\n%s", config)
+	}
+
+	if motd, _ := fs.ReadFile("plugins/LuckPerms/motd.txt"); string(motd) != "Welcome to Lobby\n" {
+		t.Fatalf("variable not substituted: %q", motd)
+	}
+
+	if drift, err := c.OverlayDrift(); err != nil || len(drift) != 0 {
+		t.Fatalf("drift right after applying: %v %v", drift, err)
+	}
+
+	// Hand edits are reported and left alone
+	fs.WriteFile("plugins/LuckPerms/motd.txt", []byte("edited\n"), 0644)
+	drift, _ := c.OverlayDrift()
+	if len(drift) != 1 || drift[0].Status != DriftModified {
+		t.Fatalf("hand edit not reported: %v", drift)
+	}
+
+	if result, _ := c.ApplyOverlays(false); len(result.Skipped) != 1 {
+		t.Fatalf("hand edit overwritten: %+v", result)
+	}
+
+	if result, _ := c.ApplyOverlays(true); len(result.Written) != 1 {
+		t.Fatalf("forced apply didn't overwrite: %+v", result)
+	}
+
+	os.WriteFile(filepath.Join(base, "motd.txt"), []byte("Hello ${SERVER}\n"), 0644)
+	if drift, _ := c.OverlayDrift(); len(drift) != 1 || drift[0].Status != DriftOutdated {
+		t.Fatalf("base change not reported: %v", drift)
+	}
+}
+
+func TestOverlayEscaping(t *testing.T) {
+	base := t.TempDir()
+	os.WriteFile(filepath.Join(base, "config.yml"), []byte(
+		"# storage settings\npassword: ${secret:db-password}\nport: ${PORT}\nmotd: ${MOTD}\n"), 0644)
+
+	t.Setenv("BUCKET_SECRET_DB_PASSWORD", "*p4ss: #word'\ninjected: true")
+
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	c := &OpenContext{Fs: fs, Context: Context{Variables: map[string]string{"PORT": "3306", "MOTD": "&welcome"}},
+		LocalConfig: &Config{
+			Overlays: []OverlayConfig{{Base: base, Target: "plugins/LuckPerms"}},
+			Secrets:  []SecretConfig{{Provider: "env"}},
+		}}
+
+	if _, err := c.ApplyOverlays(false); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := fs.ReadFile("plugins/LuckPerms/config.yml")
+
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		t.Fatalf("rendered config doesn't parse: %v\n%s", err, data)
+	}
+
+	if len(config) != 3 || config["password"] != "*p4ss: #word'\ninjected: true" ||
+		config["port"] != 3306 || config["motd"] != "&welcome" {
+		t.Fatalf("wrong rendered values: %#v", config)
+	}
+}
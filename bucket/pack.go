@@ -0,0 +1,392 @@
+package bucket
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/MRtecno98/afero"
+	"github.com/hashicorp/go-multierror"
+)
+
+const (
+	LockfileName  = "bucket.lock"
+	PackExtension = ".bucket.zip"
+)
+
+// Lockfile pins the plugins of a server to the repository versions
+// they were resolved to, so that they can be downloaded again
+type Lockfile struct {
+	Platform    string      `json:"platform"`
+	GameVersion string      `json:"game_version,omitempty"`
+	Plugins     []LockEntry `json:"plugins"`
+}
+
+type LockEntry struct {
+	Name       string `json:"name"`
+	File       string `json:"file"`
+	Repository string `json:"repository"`
+	Identifier string `json:"identifier"`
+	Version    string `json:"version,omitempty"`
+	VersionID  string `json:"version_id,omitempty"`
+	Sha256     string `json:"sha256"`
+}
+
+// BuildLockfile locks the resolved plugins, the unresolved ones
+// are returned apart since they can't be downloaded again
+func (c *OpenContext) BuildLockfile(ctx context.Context) (*Lockfile, []*LocalPlugin, error) {
+	if c.Platform == nil {
+		return nil, nil, errors.New("no platform detected")
+	}
+
+	plugins, _, err := c.Platform.Plugins()
+	if err != nil && len(plugins) == 0 {
+		return nil, nil, err
+	}
+
+	return c.lockPlugins(ctx, plugins)
+}
+
+func (c *OpenContext) lockPlugins(ctx context.Context, plugins []Plugin) (*Lockfile, []*LocalPlugin, error) {
+	lock := &Lockfile{Platform: c.PlatformName(), GameVersion: c.GameVersion(), Plugins: []LockEntry{}}
+
+	var unlocked []*LocalPlugin
+	for _, pl := range plugins {
+		local, ok := pl.(*LocalPlugin)
+		if !ok {
+			continue
+		}
+
+		rec, ok := c.Plugins().GetFirst(pl.GetIdentifier())
+		if !ok {
+			unlocked = append(unlocked, local)
+			continue
+		}
+
+		hash, err := local.Hash()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		entry := LockEntry{
+			Name:       pl.GetName(),
+			File:       path.Base(filepath.ToSlash(local.File.Name())),
+			Repository: rec.Repository.GetName(),
+			Identifier: rec.RemoteIdentifier,
+			Sha256:     hash,
+		}
+
+		if v, ok := pl.(Versionable); ok {
+			entry.Version = v.GetVersion()
+		}
+
+		// The version name alone can match the builds for other loaders
+		if ver, err := lockedVersion(ctx, &rec, c.Platform.Type(), entry); err == nil {
+			entry.VersionID = ver.GetVersionIdentifier()
+		} else {
+			log.Printf("warn: %s %s: %v, locking the version name only\n", entry.Name, entry.Version, err)
+		}
+
+		lock.Plugins = append(lock.Plugins, entry)
+	}
+
+	slices.SortFunc(lock.Plugins, func(a, b LockEntry) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+
+	return lock, unlocked, nil
+}
+
+func (c *OpenContext) WriteLockfile(lock *Lockfile) error {
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return c.Fs.WriteFile(LockfileName, append(data, '\n'), 0644)
+}
+
+func (c *OpenContext) ReadLockfile() (*Lockfile, error) {
+	data, err := c.Fs.ReadFile(LockfileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var lock Lockfile
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("%s: %w", LockfileName, err)
+	}
+
+	return &lock, nil
+}
+
+// Pack writes a server template: the config, the lockfile, the plugin and
+// server configs and optionally the worlds. Plugin jars are left out and
+// replaced by their lock entries, unless they are unresolved. Files written
+// by the overlays are left out too, they can hold rendered secrets
+func (c *OpenContext) Pack(ctx context.Context, w io.Writer, worlds bool) (*Lockfile, []*LocalPlugin, error) {
+	lock, unlocked, err := c.BuildLockfile(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := c.WriteLockfile(lock); err != nil {
+		return nil, nil, err
+	}
+
+	roots, err := c.ConfigRoots()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if worlds {
+		w, err := c.Worlds()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		roots = append(w, roots...)
+	}
+
+	rendered, err := c.overlayState()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	roots = append(roots, LockfileName)
+	for _, pl := range unlocked {
+		roots = append(roots, filepath.ToSlash(pl.File.Name()))
+	}
+
+	archive := zip.NewWriter(w)
+	for _, root := range slices.Compact(roots) {
+		if err := c.Fs.Walk(root, func(name string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+
+			if _, ok := rendered[filepath.ToSlash(name)]; ok {
+				return nil
+			}
+
+			return c.packFile(archive, filepath.ToSlash(name), info)
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return lock, unlocked, archive.Close()
+}
+
+func (c *OpenContext) packFile(archive *zip.Writer, name string, info os.FileInfo) error {
+	src, err := c.Fs.Open(name)
+	if err != nil {
+		return err
+	}
+
+	defer src.Close()
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+
+	header.Name = name
+	header.Method = zip.Deflate
+
+	w, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, src)
+	return err
+}
+
+// Unpack extracts a server template in the context
+// and downloads the plugins listed in its lockfile
+func (c *OpenContext) Unpack(ctx context.Context, r io.ReaderAt, size int64) (*Lockfile, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range archive.File {
+		if !filepath.IsLocal(f.Name) {
+			return nil, fmt.Errorf("unsafe path in archive: %s", f.Name)
+		}
+
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		if err := c.unpackFile(f); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+	}
+
+	// The repositories of the lock entries are in the unpacked config
+	if err := c.ReloadConfig(); err != nil {
+		return nil, err
+	}
+
+	lock, err := c.ReadLockfile()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Platform == nil {
+		plt := GetPlatform(lock.Platform)
+		if plt == nil {
+			return nil, fmt.Errorf("unknown platform: %s", lock.Platform)
+		}
+
+		c.Platform = plt.Build(c)
+	}
+
+	return lock, c.InstallLocked(ctx, lock)
+}
+
+func (c *OpenContext) unpackFile(f *zip.File) error {
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+
+	defer src.Close()
+
+	if err := c.Fs.MkdirAll(path.Dir(f.Name), 0755); err != nil {
+		return err
+	}
+
+	dst, err := c.Fs.OpenFile(f.Name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+
+	return dst.Close()
+}
+
+// InstallLocked downloads the locked plugins that aren't already installed
+func (c *OpenContext) InstallLocked(ctx context.Context, lock *Lockfile) error {
+	if err := c.Fs.MkdirAll(c.Platform.PluginsFolder(), 0755); err != nil {
+		return err
+	}
+
+	folder := afero.Afero{Fs: afero.NewBasePathFs(c.Fs, c.Platform.PluginsFolder())}
+
+	var errs *multierror.Error
+	for _, entry := range lock.Plugins {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := c.installLocked(ctx, folder, entry); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("%s: %w", entry.Name, err))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+func (c *OpenContext) installLocked(ctx context.Context, folder afero.Afero, entry LockEntry) error {
+	if hash, err := hashPath(folder, entry.File); err == nil && hash == entry.Sha256 {
+		return nil
+	}
+
+	repo := c.RepositoryByNameOrProvider(entry.Repository)
+	if repo == nil {
+		return fmt.Errorf("repository %s not found", entry.Repository)
+	}
+
+	remote, err := repo.Get(ctx, entry.Identifier)
+	if err != nil {
+		return err
+	}
+
+	ver, err := lockedVersion(ctx, remote, c.Platform.Type(), entry)
+	if err != nil {
+		log.Printf("warn: %s %s not found (%v), using the latest compatible version\n",
+			entry.Name, entry.Version, err)
+
+		if ver, err = remote.GetLatestCompatible(ctx, c.Platform.Type()); err != nil {
+			return err
+		}
+	}
+
+	files, err := ver.GetFiles(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if f.Optional() {
+			continue
+		}
+
+		if err := DownloadFile(ctx, folder, f); err != nil {
+			return err
+		}
+
+		if f.Name() == entry.File {
+			if hash, err := hashPath(folder, f.Name()); err == nil && hash != entry.Sha256 {
+				log.Printf("warn: %s differs from the locked jar\n", entry.File)
+			}
+		}
+	}
+
+	return nil
+}
+
+func hashPath(fs afero.Fs, name string) (string, error) {
+	file, err := fs.Open(name)
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+	return HashFile(file)
+}
+
+// lockedVersion finds the version of a lock entry by its identifier, or by
+// name among the versions compatible with the platform if not locked or
+// if the locked one is for another platform (e.g. mirrored servers)
+func lockedVersion(ctx context.Context, remote RemotePlugin, platform PlatformType, entry LockEntry) (RemoteVersion, error) {
+	if entry.VersionID != "" {
+		ver, err := remote.GetVersionByID(ctx, entry.VersionID)
+		if err != nil || ver.Compatible(platform) {
+			return ver, err
+		}
+	}
+
+	if entry.Version == "" {
+		return nil, errors.New("no version locked")
+	}
+
+	versions, err := remote.GetVersions(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range versions {
+		if v.Compatible(platform) && sameVersion(v.GetVersion(), entry.Version) {
+			return v, nil
+		}
+	}
+
+	return nil, errors.New("version not available")
+}
@@ -0,0 +1,235 @@
+package bucket
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MRtecno98/afero"
+)
+
+const testPackConfig = `platform: stub
+repositories:
+  - name: mirror
+    provider: fake
+`
+
+// fakeRepository serves every plugin with a single version and jar
+type fakeRepository struct {
+	Repository
+}
+
+func (r *fakeRepository) Provider() string         { return "fake" }
+func (r *fakeRepository) PluginType() reflect.Type { return reflect.TypeOf(fakeVersion{}) }
+
+func (r *fakeRepository) Get(ctx context.Context, identifier string) (RemotePlugin, error) {
+	return &fakeVersion{identifier: identifier}, nil
+}
+
+type fakeVersion struct {
+	RemoteVersion
+	identifier string
+}
+
+func (v *fakeVersion) GetName() string       { return v.identifier }
+func (v *fakeVersion) GetIdentifier() string { return v.identifier }
+func (v *fakeVersion) GetVersion() string    { return "1.0" }
+
+func (v *fakeVersion) GetVersionIdentifier() string          { return v.identifier + "-1.0" }
+func (v *fakeVersion) Compatible(platform PlatformType) bool { return true }
+
+func (v *fakeVersion) GetVersionIdentifiers(ctx context.Context) ([]string, error) {
+	return []string{v.GetVersionIdentifier()}, nil
+}
+
+func (v *fakeVersion) GetVersions(ctx context.Context, limit int) ([]RemoteVersion, error) {
+	return []RemoteVersion{v}, nil
+}
+
+func (v *fakeVersion) GetFiles(ctx context.Context) ([]RemoteFile, error) {
+	return []RemoteFile{fakeFile(v.identifier + ".jar")}, nil
+}
+
+type fakeFile string
+
+func (f fakeFile) Name() string   { return string(f) }
+func (f fakeFile) Optional() bool { return false }
+func (f fakeFile) Verify() error  { return nil }
+
+func (f fakeFile) Download(ctx context.Context) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("jar")), nil
+}
+
+func init() {
+	RegisterRepository("fake", func(context.Context, *OpenContext, map[string]string) (Repository, error) {
+		return &fakeRepository{}, nil
+	})
+}
+
+func TestPackUnpack(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	fs.MkdirAll("plugins/Essentials", 0755)
+	fs.MkdirAll("world/region", 0755)
+	fs.WriteFile(ConfigName, []byte(testPackConfig), 0644)
+	fs.WriteFile("server.properties", []byte("motd=hi\n"), 0644)
+	fs.WriteFile("plugins/Essentials/config.yml", []byte("locale: en\n"), 0644)
+	fs.WriteFile("plugins/Essentials/database.yml", []byte("password: hunter2\n"), 0644)
+	fs.WriteFile("plugins/Essentials.jar", []byte("jar"), 0644)
+	fs.WriteFile("world/region/r.0.0.mca", []byte("region"), 0644)
+
+	stub := &stubPlatform{PlatformType{Name: "stub"}}
+	c := &OpenContext{Fs: fs, Platform: stub, LocalConfig: &Config{}}
+
+	// Overlay output can hold secrets
+	if err := c.saveOverlayState(map[string]string{"plugins/Essentials/database.yml": "sum"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	lock, _, err := c.Pack(context.Background(), &buf, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lock.Platform != "stub" {
+		t.Fatalf("wrong platform locked: %s", lock.Platform)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packed := map[string]bool{}
+	for _, f := range reader.File {
+		packed[f.Name] = true
+	}
+
+	for _, name := range []string{ConfigName, LockfileName, "server.properties", "plugins/Essentials/config.yml"} {
+		if !packed[name] {
+			t.Errorf("%s missing from the archive", name)
+		}
+	}
+
+	for _, name := range []string{"plugins/Essentials.jar", "plugins/Essentials/database.yml", "world/region/r.0.0.mca"} {
+		if packed[name] {
+			t.Errorf("%s should not be packed", name)
+		}
+	}
+
+	target := afero.Afero{Fs: afero.NewMemMapFs()}
+	u := &OpenContext{Fs: target, Platform: stub, LocalConfig: &Config{}}
+	if _, err := u.Unpack(context.Background(), bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := target.ReadFile("plugins/Essentials/config.yml"); string(data) != "locale: en\n" {
+		t.Fatalf("plugin config not unpacked: %q", data)
+	}
+}
+
+// Lock entries are installed through the repositories of the unpacked config
+func TestUnpackNamedRepository(t *testing.T) {
+	lock, _ := json.Marshal(Lockfile{Platform: "stub", Plugins: []LockEntry{
+		{Name: "Essentials", File: "essentials.jar", Repository: "mirror", Identifier: "essentials", Version: "1.0"},
+	}})
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, data := range map[string][]byte{ConfigName: []byte(testPackConfig), LockfileName: lock} {
+		f, _ := w.Create(name)
+		f.Write(data)
+	}
+
+	w.Close()
+
+	target := afero.Afero{Fs: afero.NewMemMapFs()}
+	c := &OpenContext{Fs: target, Platform: &stubPlatform{PlatformType{Name: "stub"}},
+		LocalConfig: &Config{}, Repositories: map[string]NamedRepository{}}
+
+	if _, err := c.Unpack(context.Background(), bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := target.ReadFile("plugins/essentials.jar"); string(data) != "jar" {
+		t.Fatalf("locked plugin not installed: %q", data)
+	}
+}
+
+func TestUnpackUnsafePath(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, _ := w.Create("../escape.yml")
+	f.Write([]byte("x"))
+	w.Close()
+
+	c := &OpenContext{Fs: afero.Afero{Fs: afero.NewMemMapFs()}, LocalConfig: &Config{}}
+	if _, err := c.Unpack(context.Background(), bytes.NewReader(buf.Bytes()), int64(buf.Len())); err == nil {
+		t.Fatal("archive escaping the context was unpacked")
+	}
+}
+
+// loaderPlugin has the same version built for several loaders
+type loaderPlugin struct {
+	RemotePlugin
+	versions []RemoteVersion
+}
+
+func (p *loaderPlugin) GetVersions(ctx context.Context, limit int) ([]RemoteVersion, error) {
+	return p.versions, nil
+}
+
+func (p *loaderPlugin) GetVersionByID(ctx context.Context, identifier string) (RemoteVersion, error) {
+	for _, v := range p.versions {
+		if v.GetVersionIdentifier() == identifier {
+			return v, nil
+		}
+	}
+
+	return nil, errors.New("version not found")
+}
+
+type loaderVersion struct {
+	RemoteVersion
+	id, loader string
+}
+
+func (v *loaderVersion) GetVersion() string                    { return "2.0" }
+func (v *loaderVersion) GetVersionIdentifier() string          { return v.id }
+func (v *loaderVersion) Compatible(platform PlatformType) bool { return platform.Name == v.loader }
+
+func TestLockedVersion(t *testing.T) {
+	remote := &loaderPlugin{versions: []RemoteVersion{
+		&loaderVersion{id: "fabric-build", loader: "fabric"},
+		&loaderVersion{id: "paper-build", loader: "paper"},
+	}}
+
+	paper := PlatformType{Name: "paper"}
+
+	ver, err := lockedVersion(context.Background(), remote, paper, LockEntry{Version: "2.0"})
+	if err != nil || ver.GetVersionIdentifier() != "paper-build" {
+		t.Fatalf("wrong version for the platform: %v %v", ver, err)
+	}
+
+	// Locked identifiers win unless they're built for another platform
+	ver, err = lockedVersion(context.Background(), remote, PlatformType{Name: "fabric"},
+		LockEntry{Version: "2.0", VersionID: "fabric-build"})
+	if err != nil || ver.GetVersionIdentifier() != "fabric-build" {
+		t.Fatalf("locked identifier ignored: %v %v", ver, err)
+	}
+
+	ver, err = lockedVersion(context.Background(), remote, paper, LockEntry{Version: "2.0", VersionID: "fabric-build"})
+	if err != nil || ver.GetVersionIdentifier() != "paper-build" {
+		t.Fatalf("locked identifier of another platform installed: %v %v", ver, err)
+	}
+
+	if _, err := lockedVersion(context.Background(), remote, PlatformType{Name: "velocity"}, LockEntry{Version: "2.0"}); err == nil {
+		t.Fatal("incompatible version accepted")
+	}
+}
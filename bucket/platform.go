@@ -1,9 +1,11 @@
 package bucket
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 
 	"github.com/MRtecno98/afero"
@@ -14,15 +16,34 @@ import (
 type PlatformType struct {
 	Name       string
 	Compatible []string
-	Install    func(context *OpenContext) error
-	Detect     func(context *OpenContext) (Platform, error)
-	Build      func(context *OpenContext) Platform
+
+	// Plugins must declare support for strict platforms explicitly,
+	// supporting one of the compatible platforms is not enough
+	Strict bool
+
+	// Mod loaders install mods instead of plugins
+	Mods bool
+
+	// Server config files read by the platform with their known keys,
+	// the ones of the compatible platforms are read too
+	ServerConfigs map[string]ServerSchema
+
+	// Downloads the server in the context, latest version if empty
+	Install func(context *OpenContext, version string) error
+	Detect  func(context *OpenContext) (Platform, error)
+	Build   func(context *OpenContext) Platform
 }
 
 type PlatformCompatible interface {
 	Compatible(PlatformType) bool
 }
 
+// DeclaredPlatforms is implemented by plugins that explicitly
+// list the platforms they support
+type DeclaredPlatforms interface {
+	DeclaredPlatforms() []string
+}
+
 type PluginProvider interface {
 	PluginsFolder() string
 	Plugins() ([]Plugin, []error, error)
@@ -32,6 +53,9 @@ type PluginProvider interface {
 type Platform interface {
 	Type() PlatformType
 
+	// Minecraft version of the server, empty if unknown
+	GameVersion() string
+
 	PluginProvider
 }
 
@@ -57,10 +81,49 @@ func (t PlatformType) EveryCompatible() []string {
 	return FindAllCompatible(&t)
 }
 
+// ServerSchemas collects the server config files of the platform
+// and of every platform it's compatible with
+func (t PlatformType) ServerSchemas() map[string]ServerSchema {
+	schemas := make(map[string]ServerSchema)
+	for _, name := range t.EveryCompatible() {
+		if plt := GetPlatform(name); plt != nil && name != t.Name {
+			for file, schema := range plt.ServerConfigs {
+				schemas[file] = append(schemas[file], schema...)
+			}
+		}
+	}
+
+	for file, schema := range t.ServerConfigs {
+		schemas[file] = append(schemas[file], schema...)
+	}
+
+	return schemas
+}
+
 func (t PlatformType) AnyCompatible(platforms []string) bool {
 	return len(intersect.Hash(FindAllCompatible(&t), platforms)) > 0
 }
 
+// Supports tells if the plugin can run on the platform, which
+// for strict platforms requires the plugin to declare it
+func (t PlatformType) Supports(p Plugin) bool {
+	if !t.Strict {
+		return true
+	}
+
+	d, ok := Unwrap(p).(DeclaredPlatforms)
+	return ok && slices.Contains(d.DeclaredPlatforms(), t.Name)
+}
+
+func (p *LocalPlugin) Compatible(platform PlatformType) bool {
+	return platform.Supports(p)
+}
+
+// JarPathContains matches a path inside a jar regardless of the separator
+func JarPathContains(path string, fragment string) bool {
+	return strings.Contains(strings.ReplaceAll(path, "\\", "/"), fragment)
+}
+
 type Decoder func(pl afero.File, descriptor io.Reader, out any) error
 
 func BufferedDecode(decode func(in []byte, out any) error) Decoder {
@@ -74,6 +137,37 @@ func BufferedDecode(decode func(in []byte, out any) error) Decoder {
 	}
 }
 
+// JSONDecode decodes the descriptor as a stream, without buffering it
+func JSONDecode(pl afero.File, descriptor io.Reader, out any) error {
+	return json.NewDecoder(descriptor).Decode(out)
+}
+
+func (p ContextPlatform) GameVersion() string {
+	return p.Context.GameVersion()
+}
+
+func (p *PluginCachePlatform) GameVersion() string {
+	if v, ok := p.PluginProvider.(interface{ GameVersion() string }); ok {
+		return v.GameVersion()
+	}
+
+	return ""
+}
+
+// SameMinorVersion tells if two Minecraft versions share
+// the same minor release (e.g. 1.20 and 1.20.4)
+func SameMinorVersion(a, b string) bool {
+	minor := func(v string) string {
+		if parts := strings.SplitN(v, ".", 3); len(parts) >= 2 {
+			return parts[0] + "." + parts[1]
+		}
+
+		return v
+	}
+
+	return minor(a) == minor(b)
+}
+
 func (p *PluginCachePlatform) Plugins() ([]Plugin, []error, error) {
 	if p.PluginsCache != nil {
 		return p.PluginsCache, nil, nil
@@ -138,6 +232,7 @@ func (p JarPluginPlatform[PluginType]) Plugins() ([]Plugin, []error, error) {
 
 				if err != nil {
 					errs = append(errs, fmt.Errorf("unable to load %s: %w", file.Name(), err))
+					continue
 				}
 
 				plugins = append(plugins, plugin)
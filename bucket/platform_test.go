@@ -0,0 +1,49 @@
+package bucket
+
+import "testing"
+
+type declaredPlugin struct {
+	CachedRecord
+	platforms []string
+}
+
+func (p *declaredPlugin) GetIdentifier() string       { return p.Name }
+func (p *declaredPlugin) DeclaredPlatforms() []string { return p.platforms }
+
+func TestStrictPlatformSupport(t *testing.T) {
+	strict := PlatformType{Name: "folia", Compatible: []string{"paper"}, Strict: true}
+	loose := PlatformType{Name: "paper"}
+
+	supported := &declaredPlugin{platforms: []string{"folia"}}
+	unsupported := &declaredPlugin{}
+
+	if !strict.Supports(supported) || strict.Supports(unsupported) {
+		t.Fatal("strict platform must only support plugins declaring it")
+	}
+
+	if !loose.Supports(unsupported) {
+		t.Fatal("non strict platforms support every plugin")
+	}
+}
+
+func TestJarPaths(t *testing.T) {
+	if !JarPathContains("org\\spigotmc\\Main.class", "org/spigotmc") {
+		t.Error("windows separators not normalized")
+	}
+}
+
+func TestSameMinorVersion(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		same bool
+	}{
+		{"1.20", "1.20.4", true},
+		{"1.20.4", "1.20.1", true},
+		{"1.21", "1.20.4", false},
+		{"1.8", "1.8.8", true},
+	} {
+		if SameMinorVersion(c.a, c.b) != c.same {
+			t.Errorf("SameMinorVersion(%s, %s) != %v", c.a, c.b, c.same)
+		}
+	}
+}
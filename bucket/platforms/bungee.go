@@ -2,14 +2,18 @@ package platforms
 
 import (
 	"log"
-	"strings"
 
 	"github.com/MRtecno98/bucket/bucket"
 	"gopkg.in/yaml.v2"
 )
 
+const BungeeMain = "net.md_5.bungee.Bootstrap"
+
 var BungeeTypePlatform = bucket.PlatformType{
-	Name:    "bungeecoord",
+	Name: "bungeecoord",
+	ServerConfigs: map[string]bucket.ServerSchema{
+		"config.yml": BungeeSchema,
+	},
 	Install: InstallBungeecoord,
 	Detect:  DetectBungeecoord,
 	Build: func(context *bucket.OpenContext) bucket.Platform {
@@ -43,8 +47,8 @@ func NewBungeePlatform(context *bucket.OpenContext) *BungeePlatform {
 }
 
 func DetectBungeecoord(context *bucket.OpenContext) (bucket.Platform, error) {
-	res, err := bucket.DetectJarPath(context, func(path string) bool {
-		return strings.Contains(path, "net\\md_5\\bungee")
+	res, err := bucket.DetectServerJar(context, func(jar *bucket.ServerJar) bool {
+		return jar.Launcher(BungeeMain) || jar.Contains("net/md_5/bungee/")
 	})
 
 	if err != nil {
@@ -58,6 +62,6 @@ func DetectBungeecoord(context *bucket.OpenContext) (bucket.Platform, error) {
 	}
 }
 
-func InstallBungeecoord(context *bucket.OpenContext) error {
-	return nil
+func InstallBungeecoord(context *bucket.OpenContext, version string) error {
+	return installBungeecoord(context, version)
 }
@@ -0,0 +1,160 @@
+package platforms
+
+import (
+	"archive/zip"
+	"bytes"
+	"path"
+	"testing"
+
+	"github.com/MRtecno98/afero"
+	"github.com/MRtecno98/bucket/bucket"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// syntheticJar builds a jar with the given entries, a "Main-Class" entry is
+// written as the manifest main class and directories end with a slash
+func syntheticJar(t *testing.T, entries map[string]string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+
+	for name, content := range entries {
+		if name == "Main-Class" {
+			name, content = "META-INF/MANIFEST.MF",
+				"Manifest-Version: 1.0\r\nMain-Class: "+content+"\r\n\r\n"
+		}
+
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func paperclip(fork string) map[string]string {
+	return map[string]string{
+		"Main-Class":             "io.papermc.paperclip.Main",
+		"io/papermc/paperclip/":  "",
+		"META-INF/versions.list": "abc123\t1.20.4\t" + fork + "-1.20.4.jar\n",
+	}
+}
+
+func TestDetectPlatform(t *testing.T) {
+	cases := []struct {
+		platform string
+		version  string
+		files    map[string]map[string]string
+		dirs     []string
+	}{
+		{"spigot", "1.20.4", map[string]map[string]string{"spigot.jar": {
+			"Main-Class":             SpigotBundlerMain,
+			"META-INF/versions.list": "abc123\t1.20.4\tspigot-1.20.4-R0.1-SNAPSHOT.jar\n",
+		}}, nil},
+		{"spigot", "1.20.4", map[string]map[string]string{"bundler/versions/spigot-1.20.4.jar": {
+			"Main-Class":                      CraftBukkitMain,
+			"version.json":                    `{"id": "1.20.4", "name": "1.20.4"}`,
+			"org/spigotmc/SpigotConfig.class": "",
+		}}, nil},
+		{"paper", "1.20.4", map[string]map[string]string{"server.jar": paperclip("paper")}, nil},
+		{"paper", "1.16.5", map[string]map[string]string{"server.jar": {
+			"Main-Class":       "com.destroystokyo.paperclip.Paperclip",
+			"patch.properties": "patch=paper.patch\nversion=1.16.5\n",
+		}}, nil},
+		{"purpur", "1.20.4", map[string]map[string]string{"server.jar": paperclip("purpur")}, nil},
+		{"pufferfish", "1.20.4", map[string]map[string]string{"server.jar": paperclip("pufferfish")}, nil},
+		{"folia", "1.20.4", map[string]map[string]string{"server.jar": paperclip("folia")}, nil},
+		{"bungeecoord", "", map[string]map[string]string{"proxy.jar": {
+			"Main-Class": BungeeMain,
+		}}, nil},
+		{"waterfall", "", map[string]map[string]string{"proxy.jar": {
+			"Main-Class": BungeeMain,
+			"io/github/waterfallmc/waterfall/conf/WaterfallConfiguration.class": "",
+		}}, nil},
+		{"velocity", "", map[string]map[string]string{"velocity.jar": {
+			"Main-Class": VelocityMain,
+		}}, nil},
+		{"fabric", "", map[string]map[string]string{"server.jar": {
+			"Main-Class": "net.fabricmc.installer.ServerLauncher",
+		}}, nil},
+		{"fabric", "", nil, []string{".fabric"}},
+		{"quilt", "", map[string]map[string]string{"server.jar": {
+			"Main-Class": "org.quiltmc.loader.impl.launch.server.QuiltServerLauncher",
+		}}, nil},
+	}
+
+	for _, c := range cases {
+		fs := afero.Afero{Fs: afero.NewMemMapFs()}
+
+		// Invalid archives in the root must be skipped
+		fs.WriteFile("broken.jar", []byte("not a zip"), 0644)
+
+		for name, entries := range c.files {
+			fs.MkdirAll(path.Dir(name), 0755)
+			if err := fs.WriteFile(name, syntheticJar(t, entries), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, dir := range c.dirs {
+			fs.MkdirAll(dir, 0755)
+		}
+
+		oc := &bucket.OpenContext{Fs: fs, LocalConfig: &bucket.Config{}}
+
+		plt, err := bucket.DetectPlatform(oc)
+		if err != nil {
+			t.Fatalf("%s: %v", c.platform, err)
+		}
+
+		if plt == nil {
+			t.Errorf("%s: no platform detected", c.platform)
+			continue
+		}
+
+		if plt.Type().Name != c.platform {
+			t.Errorf("expected %s, detected %s", c.platform, plt.Type().Name)
+		}
+
+		if c.version != "" && plt.GameVersion() != c.version {
+			t.Errorf("%s: expected version %s, got %s", c.platform, c.version, plt.GameVersion())
+		}
+	}
+}
+
+func TestGameVersion(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	fs.WriteFile("purpur-1.20.4-2176.jar", syntheticJar(t, map[string]string{
+		"Main-Class":    "io.papermc.paperclip.Main",
+		"org/purpurmc/": "",
+	}), 0644)
+
+	oc := &bucket.OpenContext{Fs: fs, LocalConfig: &bucket.Config{}}
+	if v := oc.GameVersion(); v != "1.20.4" {
+		t.Errorf("expected version from the jar name, got %q", v)
+	}
+
+	oc.LocalConfig.MinecraftVersion = "1.20.6"
+	if v := oc.GameVersion(); v != "1.20.6" {
+		t.Errorf("expected the configured version, got %q", v)
+	}
+}
+
+func TestDetectNothing(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	fs.WriteFile("library.jar", syntheticJar(t, map[string]string{"Main-Class": "com.example.Main"}), 0644)
+
+	plt, err := bucket.DetectPlatform(&bucket.OpenContext{Fs: fs, LocalConfig: &bucket.Config{}})
+	if err != nil || plt != nil {
+		t.Fatalf("unexpected detection %v: %v", plt, err)
+	}
+}
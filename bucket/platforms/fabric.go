@@ -0,0 +1,164 @@
+package platforms
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/MRtecno98/bucket/bucket"
+)
+
+// Dependencies on the game or the loader itself, not on other mods
+var loaderDependencies = []string{"minecraft", "java", "fabricloader", "quilt_loader"}
+
+type FabricModDescriptor struct {
+	ID          string         `json:"id"`
+	Version     string         `json:"version"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Authors     []FabricPerson `json:"authors"`
+	Environment string         `json:"environment"`
+
+	Contact struct {
+		Homepage string `json:"homepage"`
+		Sources  string `json:"sources"`
+	} `json:"contact"`
+
+	// Values are version ranges, which can be strings or arrays
+	Depends    map[string]json.RawMessage `json:"depends"`
+	Recommends map[string]json.RawMessage `json:"recommends"`
+}
+
+// FabricPerson is either a plain name or an object with contact information
+type FabricPerson struct {
+	Name string `json:"name"`
+}
+
+var FabricTypePlatform = bucket.PlatformType{
+	Name: "fabric",
+	Mods: true,
+	ServerConfigs: map[string]bucket.ServerSchema{
+		"server.properties": ServerPropertiesSchema,
+	},
+	Install: InstallFabric,
+	Detect:  DetectFabric,
+	Build: func(context *bucket.OpenContext) bucket.Platform {
+		return NewFabricPlatform(context) // Go boilerplate
+	},
+}
+
+func init() {
+	bucket.RegisterPlatform(FabricTypePlatform, 30)
+}
+
+type FabricPlatform struct {
+	bucket.PluginCachePlatform
+}
+
+func (p *FabricPlatform) Type() bucket.PlatformType {
+	return FabricTypePlatform
+}
+
+func NewFabricPlatform(context *bucket.OpenContext) *FabricPlatform {
+	return &FabricPlatform{
+		PluginCachePlatform: bucket.PluginCachePlatform{
+			PluginProvider: bucket.JarPluginPlatform[FabricModDescriptor]{
+				ContextPlatform: bucket.ContextPlatform{Context: context},
+				Decode:          bucket.JSONDecode,
+				PluginFiles:     []string{"fabric.mod.json"},
+				PluginFolder:    "mods",
+			},
+		},
+	}
+}
+
+func DetectFabric(context *bucket.OpenContext) (bucket.Platform, error) {
+	res, err := bucket.DetectFiles(context, "fabric-server-launch.jar", ".fabric")
+	if err == nil && !res {
+		res, err = bucket.DetectServerJar(context, func(jar *bucket.ServerJar) bool {
+			return strings.HasPrefix(jar.MainClass(), "net.fabricmc.")
+		})
+	}
+
+	if err != nil {
+		log.Println("error during platform check:", err)
+	}
+
+	if res {
+		return NewFabricPlatform(context), nil
+	} else {
+		return nil, nil
+	}
+}
+
+func InstallFabric(context *bucket.OpenContext, version string) error {
+	return fmt.Errorf("fabric: %w", bucket.ErrInstallUnsupported)
+}
+
+func (p *FabricPerson) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &p.Name); err == nil {
+		return nil
+	}
+
+	type Alias FabricPerson
+	return json.Unmarshal(data, (*Alias)(p))
+}
+
+func (md FabricModDescriptor) GetName() string {
+	if md.Name != "" {
+		return md.Name
+	}
+
+	return md.ID
+}
+
+func (md FabricModDescriptor) GetIdentifier() string {
+	return md.ID
+}
+
+func (md FabricModDescriptor) GetVersion() string {
+	return md.Version
+}
+
+func (md FabricModDescriptor) GetAuthors() []string {
+	authors := make([]string, 0, len(md.Authors))
+	for _, a := range md.Authors {
+		if a.Name != "" {
+			authors = append(authors, a.Name)
+		}
+	}
+
+	return authors
+}
+
+func (md FabricModDescriptor) GetDescription() string {
+	return md.Description
+}
+
+func (md FabricModDescriptor) GetWebsite() string {
+	return md.Contact.Homepage
+}
+
+func (md FabricModDescriptor) GetSourceURL() string {
+	return md.Contact.Sources
+}
+
+func (md FabricModDescriptor) GetDependencies() []bucket.Dependency {
+	deps := make([]bucket.Dependency, 0, len(md.Depends)+len(md.Recommends))
+
+	for _, group := range []struct {
+		ids      map[string]json.RawMessage
+		required bool
+	}{{md.Depends, true}, {md.Recommends, false}} {
+		for _, id := range slices.Sorted(maps.Keys(group.ids)) {
+			if !slices.Contains(loaderDependencies, id) {
+				deps = append(deps, bucket.Dependency{Name: id, Required: group.required})
+			}
+		}
+	}
+
+	return deps
+}
@@ -0,0 +1,55 @@
+package platforms
+
+import (
+	"log"
+
+	"github.com/MRtecno98/bucket/bucket"
+)
+
+var FoliaTypePlatform = bucket.PlatformType{
+	Name:       "folia",
+	Compatible: []string{"paper"},
+	// Paper plugins only work if they declare folia-supported
+	Strict:  true,
+	Install: InstallFolia,
+	Detect:  DetectFolia,
+	Build: func(context *bucket.OpenContext) bucket.Platform {
+		return NewFoliaPlatform(context) // Go boilerplate
+	},
+}
+
+func init() {
+	bucket.RegisterPlatform(FoliaTypePlatform, 25)
+}
+
+type FoliaPlatform struct {
+	PaperPlatform
+}
+
+func (p *FoliaPlatform) Type() bucket.PlatformType {
+	return FoliaTypePlatform
+}
+
+func NewFoliaPlatform(context *bucket.OpenContext) *FoliaPlatform {
+	return &FoliaPlatform{*NewPaperPlatform(context)}
+}
+
+func DetectFolia(context *bucket.OpenContext) (bucket.Platform, error) {
+	res, err := bucket.DetectServerJar(context, func(jar *bucket.ServerJar) bool {
+		return jar.Embeds("folia") || jar.Contains("io/papermc/paper/threadedregions/")
+	})
+
+	if err != nil {
+		log.Println("error during platform check:", err)
+	}
+
+	if res {
+		return NewFoliaPlatform(context), nil
+	} else {
+		return nil, nil
+	}
+}
+
+func InstallFolia(context *bucket.OpenContext, version string) error {
+	return installPaperProject(context, "folia", version)
+}
@@ -0,0 +1,181 @@
+package platforms
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+
+	"github.com/MRtecno98/bucket/bucket"
+)
+
+// Download APIs used to install the servers
+var (
+	PaperAPI      = "https://api.papermc.io"
+	PurpurAPI     = "https://api.purpurmc.org"
+	BungeeJenkins = "https://ci.md-5.net"
+)
+
+const BungeeJob = "BungeeCord"
+
+type paperProject struct {
+	Versions []string `json:"versions"`
+}
+
+type paperBuilds struct {
+	Builds []struct {
+		Build     int    `json:"build"`
+		Channel   string `json:"channel"`
+		Downloads map[string]struct {
+			Name   string `json:"name"`
+			Sha256 string `json:"sha256"`
+		} `json:"downloads"`
+	} `json:"builds"`
+}
+
+type purpurBuild struct {
+	Build string `json:"build"`
+	MD5   string `json:"md5"`
+}
+
+type jenkinsBuild struct {
+	Number    int `json:"number"`
+	Artifacts []struct {
+		FileName     string `json:"fileName"`
+		RelativePath string `json:"relativePath"`
+	} `json:"artifacts"`
+	Fingerprint []struct {
+		FileName string `json:"fileName"`
+		Hash     string `json:"hash"` // MD5
+	} `json:"fingerprint"`
+}
+
+// installPaperProject installs the latest stable build of a PaperMC project
+// (paper, folia, velocity, waterfall) from the v2 downloads API
+func installPaperProject(context *bucket.OpenContext, project string, version string) error {
+	base := PaperAPI + "/v2/projects/" + url.PathEscape(project)
+
+	if version == "" {
+		var proj paperProject
+		if err := bucket.FetchJSON(context.Lock, base, &proj); err != nil {
+			return fmt.Errorf("%s: %w", project, err)
+		}
+
+		if len(proj.Versions) == 0 {
+			return fmt.Errorf("%s: no versions available", project)
+		}
+
+		version = proj.Versions[len(proj.Versions)-1]
+	}
+
+	base += "/versions/" + url.PathEscape(version)
+
+	var builds paperBuilds
+	if err := bucket.FetchJSON(context.Lock, base+"/builds", &builds); err != nil {
+		return fmt.Errorf("%s: %w", project, err)
+	}
+
+	if len(builds.Builds) == 0 {
+		return fmt.Errorf("%s: no builds for %s", project, version)
+	}
+
+	// Experimental builds only if there are no stable ones
+	build := builds.Builds[len(builds.Builds)-1]
+	for i := len(builds.Builds) - 1; i >= 0; i-- {
+		if builds.Builds[i].Channel == "default" {
+			build = builds.Builds[i]
+			break
+		}
+	}
+
+	app, ok := build.Downloads["application"]
+	if !ok {
+		return fmt.Errorf("%s: build %d has no server jar", project, build.Build)
+	}
+
+	log.Printf("downloading %s\n", app.Name)
+
+	return context.InstallServer(&bucket.ServerDownload{
+		URL:      fmt.Sprintf("%s/builds/%d/downloads/%s", base, build.Build, url.PathEscape(app.Name)),
+		FileName: app.Name,
+		Checksum: app.Sha256,
+		NewHash:  sha256.New,
+	})
+}
+
+// installPurpur installs the latest purpur build of the version
+func installPurpur(context *bucket.OpenContext, version string) error {
+	base := PurpurAPI + "/v2/purpur"
+
+	if version == "" {
+		var proj paperProject // Same format
+		if err := bucket.FetchJSON(context.Lock, base, &proj); err != nil {
+			return fmt.Errorf("purpur: %w", err)
+		}
+
+		if len(proj.Versions) == 0 {
+			return fmt.Errorf("purpur: no versions available")
+		}
+
+		version = proj.Versions[len(proj.Versions)-1]
+	}
+
+	base += "/" + url.PathEscape(version)
+
+	var build purpurBuild
+	if err := bucket.FetchJSON(context.Lock, base+"/latest", &build); err != nil {
+		return fmt.Errorf("purpur: %w", err)
+	}
+
+	name := fmt.Sprintf("purpur-%s-%s.jar", version, build.Build)
+	log.Printf("downloading %s\n", name)
+
+	return context.InstallServer(&bucket.ServerDownload{
+		URL:      base + "/" + url.PathEscape(build.Build) + "/download",
+		FileName: name,
+		Checksum: build.MD5,
+		NewHash:  md5.New,
+	})
+}
+
+// installBungeecoord installs the last successful build on md_5's jenkins,
+// which doesn't keep builds for older versions
+func installBungeecoord(context *bucket.OpenContext, version string) error {
+	if version != "" {
+		log.Printf("bungeecord: ignoring version %s, only the latest build is available\n", version)
+	}
+
+	job := BungeeJenkins + "/job/" + BungeeJob
+
+	var build jenkinsBuild
+	if err := bucket.FetchJSON(context.Lock, job+"/lastSuccessfulBuild/api/json"+
+		"?tree=number,artifacts[fileName,relativePath],fingerprint[fileName,hash]", &build); err != nil {
+		return fmt.Errorf("bungeecord: %w", err)
+	}
+
+	for _, art := range build.Artifacts {
+		if art.FileName != BungeeJob+".jar" {
+			continue
+		}
+
+		download := &bucket.ServerDownload{
+			URL:      fmt.Sprintf("%s/%d/artifact/%s", job, build.Number, art.RelativePath),
+			FileName: path.Base(art.RelativePath),
+			NewHash:  md5.New,
+		}
+
+		for _, f := range build.Fingerprint {
+			if f.FileName == art.FileName {
+				download.Checksum = f.Hash
+			}
+		}
+
+		log.Printf("downloading %s #%d\n", art.FileName, build.Number)
+
+		return context.InstallServer(download)
+	}
+
+	return fmt.Errorf("bungeecord: build %d has no server jar", build.Number)
+}
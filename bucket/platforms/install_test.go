@@ -0,0 +1,135 @@
+package platforms
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MRtecno98/afero"
+	"github.com/MRtecno98/bucket/bucket"
+)
+
+const serverJar = "server jar content"
+
+func checksum(sum []byte) string {
+	return hex.EncodeToString(sum)
+}
+
+// downloadAPIs stands in for the PaperMC, Purpur and Jenkins APIs,
+// serving jars with the given checksums
+func downloadAPIs(t *testing.T, sha256sum, md5sum string) {
+	mux := http.NewServeMux()
+
+	reply := func(pattern string, body any) {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(body)
+		})
+	}
+
+	jar := func(pattern string) {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(serverJar))
+		})
+	}
+
+	reply("/v2/projects/paper", map[string]any{"versions": []string{"1.20.4", "1.21.1"}})
+	reply("/v2/projects/paper/versions/1.21.1/builds", map[string]any{"builds": []map[string]any{
+		{"build": 130, "channel": "default", "downloads": map[string]any{
+			"application": map[string]string{"name": "paper-1.21.1-130.jar", "sha256": sha256sum}}},
+		{"build": 131, "channel": "experimental", "downloads": map[string]any{
+			"application": map[string]string{"name": "paper-1.21.1-131.jar", "sha256": "unused"}}},
+	}})
+	jar("/v2/projects/paper/versions/1.21.1/builds/130/downloads/paper-1.21.1-130.jar")
+
+	reply("/v2/purpur/1.21.1/latest", map[string]string{"build": "2300", "md5": md5sum})
+	jar("/v2/purpur/1.21.1/2300/download")
+
+	reply("/job/BungeeCord/lastSuccessfulBuild/api/json", map[string]any{
+		"number":      1900,
+		"artifacts":   []map[string]string{{"fileName": "BungeeCord.jar", "relativePath": "bootstrap/target/BungeeCord.jar"}},
+		"fingerprint": []map[string]string{{"fileName": "BungeeCord.jar", "hash": md5sum}},
+	})
+	jar("/job/BungeeCord/1900/artifact/bootstrap/target/BungeeCord.jar")
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	// Keeps the test responses out of the shared cache in the home directory
+	api, download := bucket.APIClient, bucket.DownloadClient
+	bucket.APIClient, bucket.DownloadClient = server.Client, server.Client
+	t.Cleanup(func() { bucket.APIClient, bucket.DownloadClient = api, download })
+
+	apis := []*string{&PaperAPI, &PurpurAPI, &BungeeJenkins}
+	old := []string{PaperAPI, PurpurAPI, BungeeJenkins}
+	for _, api := range apis {
+		*api = server.URL
+	}
+
+	t.Cleanup(func() {
+		for i, api := range apis {
+			*api = old[i]
+		}
+	})
+}
+
+func installContext() *bucket.OpenContext {
+	return &bucket.OpenContext{
+		Fs:          afero.Afero{Fs: afero.NewMemMapFs()},
+		Lock:        context.Background(),
+		LocalConfig: &bucket.Config{},
+	}
+}
+
+func TestInstall(t *testing.T) {
+	sha := sha256.Sum256([]byte(serverJar))
+	sum := md5.Sum([]byte(serverJar))
+	downloadAPIs(t, checksum(sha[:]), checksum(sum[:]))
+
+	cases := []struct {
+		platform bucket.PlatformType
+		version  string
+		jar      string
+	}{
+		{PaperTypePlatform, "", "paper-1.21.1-130.jar"},
+		{PurpurTypePlatform, "1.21.1", "purpur-1.21.1-2300.jar"},
+		{BungeeTypePlatform, "", "BungeeCord.jar"},
+	}
+
+	for _, c := range cases {
+		oc := installContext()
+		if err := c.platform.Install(oc, c.version); err != nil {
+			t.Fatalf("%s: %v", c.platform.Name, err)
+		}
+
+		if data, err := oc.Fs.ReadFile(c.jar); err != nil || string(data) != serverJar {
+			t.Errorf("%s: unexpected jar %q: %v", c.platform.Name, data, err)
+		}
+	}
+}
+
+func TestInstallChecksumMismatch(t *testing.T) {
+	downloadAPIs(t, "bad", "bad")
+
+	for _, plt := range []bucket.PlatformType{PaperTypePlatform, BungeeTypePlatform} {
+		oc := installContext()
+		if err := plt.Install(oc, ""); err == nil {
+			t.Fatalf("%s: expected a checksum error", plt.Name)
+		}
+
+		if files, _ := oc.Fs.ReadDir("/"); len(files) != 0 {
+			t.Errorf("%s: files left behind after a failed install", plt.Name)
+		}
+	}
+}
+
+func TestInstallSpigot(t *testing.T) {
+	if err := SpigotTypePlatform.Install(installContext(), ""); !errors.Is(err, bucket.ErrInstallUnsupported) {
+		t.Fatalf("expected an unsupported install, got %v", err)
+	}
+}
@@ -2,16 +2,27 @@ package platforms
 
 import (
 	"log"
-	"strings"
 
 	"github.com/MRtecno98/bucket/bucket"
 )
 
+// Main classes of every paperclip launcher version, also used by paper forks
+var PaperclipMains = []string{
+	"io.papermc.paperclip.Main",
+	"io.papermc.paperclip.Paperclip",
+	"com.destroystokyo.paperclip.Paperclip",
+}
+
 var PaperTypePlatform = bucket.PlatformType{
 	Name:       "paper",
 	Compatible: []string{"spigot"},
-	Install:    InstallPaper,
-	Detect:     DetectPaper,
+	ServerConfigs: map[string]bucket.ServerSchema{
+		"config/paper-global.yml":         PaperGlobalSchema,
+		"config/paper-world-defaults.yml": PaperWorldSchema,
+		"paper.yml":                       LegacyPaperSchema,
+	},
+	Install: InstallPaper,
+	Detect:  DetectPaper,
 	Build: func(context *bucket.OpenContext) bucket.Platform {
 		return NewPaperPlatform(context) // Go boilerplate
 	},
@@ -44,8 +55,9 @@ func NewPaperPlatform(context *bucket.OpenContext) *PaperPlatform {
 }
 
 func DetectPaper(context *bucket.OpenContext) (bucket.Platform, error) {
-	res, err := bucket.DetectJarPath(context, func(path string) bool {
-		return strings.Contains(path, "paperclip")
+	res, err := bucket.DetectServerJar(context, func(jar *bucket.ServerJar) bool {
+		return jar.Launcher(PaperclipMains...) || jar.Patch != nil ||
+			jar.Embeds("paper") || jar.Contains("io/papermc/paper/")
 	})
 
 	if err != nil {
@@ -59,6 +71,6 @@ func DetectPaper(context *bucket.OpenContext) (bucket.Platform, error) {
 	}
 }
 
-func InstallPaper(context *bucket.OpenContext) error {
-	return nil
+func InstallPaper(context *bucket.OpenContext, version string) error {
+	return installPaperProject(context, "paper", version)
 }
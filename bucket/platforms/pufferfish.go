@@ -0,0 +1,57 @@
+package platforms
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/MRtecno98/bucket/bucket"
+)
+
+var PufferfishTypePlatform = bucket.PlatformType{
+	Name:       "pufferfish",
+	Compatible: []string{"paper"},
+	ServerConfigs: map[string]bucket.ServerSchema{
+		"pufferfish.yml": PufferfishSchema,
+	},
+	Install: InstallPufferfish,
+	Detect:  DetectPufferfish,
+	Build: func(context *bucket.OpenContext) bucket.Platform {
+		return NewPufferfishPlatform(context) // Go boilerplate
+	},
+}
+
+func init() {
+	bucket.RegisterPlatform(PufferfishTypePlatform, 15)
+}
+
+type PufferfishPlatform struct {
+	PaperPlatform
+}
+
+func (p *PufferfishPlatform) Type() bucket.PlatformType {
+	return PufferfishTypePlatform
+}
+
+func NewPufferfishPlatform(context *bucket.OpenContext) *PufferfishPlatform {
+	return &PufferfishPlatform{*NewPaperPlatform(context)}
+}
+
+func DetectPufferfish(context *bucket.OpenContext) (bucket.Platform, error) {
+	res, err := bucket.DetectServerJar(context, func(jar *bucket.ServerJar) bool {
+		return jar.Embeds("pufferfish") || jar.Patches("pufferfish") || jar.Contains("gg/pufferfish/")
+	})
+
+	if err != nil {
+		log.Println("error during platform check:", err)
+	}
+
+	if res {
+		return NewPufferfishPlatform(context), nil
+	} else {
+		return nil, nil
+	}
+}
+
+func InstallPufferfish(context *bucket.OpenContext, version string) error {
+	return fmt.Errorf("pufferfish: %w", bucket.ErrInstallUnsupported)
+}
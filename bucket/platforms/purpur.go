@@ -2,7 +2,6 @@ package platforms
 
 import (
 	"log"
-	"strings"
 
 	"github.com/MRtecno98/bucket/bucket"
 )
@@ -10,8 +9,11 @@ import (
 var PurpurTypePlatform = bucket.PlatformType{
 	Name:       "purpur",
 	Compatible: []string{"paper"},
-	Install:    InstallPurpur,
-	Detect:     DetectPurpur,
+	ServerConfigs: map[string]bucket.ServerSchema{
+		"purpur.yml": PurpurSchema,
+	},
+	Install: InstallPurpur,
+	Detect:  DetectPurpur,
 	Build: func(context *bucket.OpenContext) bucket.Platform {
 		return NewPurpurPlatform(context) // Go boilerplate
 	},
@@ -34,8 +36,8 @@ func NewPurpurPlatform(context *bucket.OpenContext) *PurpurPlatform {
 }
 
 func DetectPurpur(context *bucket.OpenContext) (bucket.Platform, error) {
-	res, err := bucket.DetectJarPath(context, func(path string) bool {
-		return strings.Contains(path, "purpurmc")
+	res, err := bucket.DetectServerJar(context, func(jar *bucket.ServerJar) bool {
+		return jar.Embeds("purpur") || jar.Patches("purpur") || jar.Contains("org/purpurmc/")
 	})
 
 	if err != nil {
@@ -49,6 +51,6 @@ func DetectPurpur(context *bucket.OpenContext) (bucket.Platform, error) {
 	}
 }
 
-func InstallPurpur(context *bucket.OpenContext) error {
-	return nil
+func InstallPurpur(context *bucket.OpenContext, version string) error {
+	return installPurpur(context, version)
 }
@@ -0,0 +1,194 @@
+package platforms
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/MRtecno98/afero"
+	"github.com/MRtecno98/bucket/bucket"
+)
+
+type QuiltModDescriptor struct {
+	Loader struct {
+		Group   string            `json:"group"`
+		ID      string            `json:"id"`
+		Version string            `json:"version"`
+		Depends []QuiltDependency `json:"depends"`
+
+		Metadata struct {
+			Name         string            `json:"name"`
+			Description  string            `json:"description"`
+			Contributors map[string]string `json:"contributors"`
+
+			Contact struct {
+				Homepage string `json:"homepage"`
+				Sources  string `json:"sources"`
+			} `json:"contact"`
+		} `json:"metadata"`
+	} `json:"quilt_loader"`
+}
+
+// QuiltDependency is either a plain mod id or an object
+type QuiltDependency struct {
+	ID       string `json:"id"`
+	Optional bool   `json:"optional"`
+}
+
+var QuiltTypePlatform = bucket.PlatformType{
+	Name: "quilt",
+	// Quilt loads fabric mods too
+	Compatible: []string{"fabric"},
+	Mods:       true,
+	Install:    InstallQuilt,
+	Detect:     DetectQuilt,
+	Build: func(context *bucket.OpenContext) bucket.Platform {
+		return NewQuiltPlatform(context) // Go boilerplate
+	},
+}
+
+func init() {
+	bucket.RegisterPlatform(QuiltTypePlatform, 35)
+}
+
+type QuiltPlatform struct {
+	bucket.PluginCachePlatform
+}
+
+func (p *QuiltPlatform) Type() bucket.PlatformType {
+	return QuiltTypePlatform
+}
+
+// NewQuiltPlatform reads quilt mods first, fabric ones otherwise
+func NewQuiltPlatform(context *bucket.OpenContext) *QuiltPlatform {
+	return &QuiltPlatform{
+		PluginCachePlatform: bucket.PluginCachePlatform{
+			PluginProvider: bucket.JarPluginPlatform[QuiltModDescriptor]{
+				ContextPlatform: bucket.ContextPlatform{Context: context},
+				Decode:          decodeQuiltOrFabric,
+				PluginFiles:     []string{"quilt.mod.json", "fabric.mod.json"},
+				PluginFolder:    "mods",
+			},
+		},
+	}
+}
+
+func DetectQuilt(context *bucket.OpenContext) (bucket.Platform, error) {
+	res, err := bucket.DetectFiles(context, "quilt-server-launch.jar", ".quilt")
+	if err == nil && !res {
+		res, err = bucket.DetectServerJar(context, func(jar *bucket.ServerJar) bool {
+			return strings.HasPrefix(jar.MainClass(), "org.quiltmc.")
+		})
+	}
+
+	if err != nil {
+		log.Println("error during platform check:", err)
+	}
+
+	if res {
+		return NewQuiltPlatform(context), nil
+	} else {
+		return nil, nil
+	}
+}
+
+func InstallQuilt(context *bucket.OpenContext, version string) error {
+	return fmt.Errorf("quilt: %w", bucket.ErrInstallUnsupported)
+}
+
+// decodeQuiltOrFabric converts fabric descriptors to the quilt format,
+// so that both kinds of mods can be listed by the same provider
+func decodeQuiltOrFabric(pl afero.File, descriptor io.Reader, out any) error {
+	data, err := io.ReadAll(descriptor)
+	if err != nil {
+		return err
+	}
+
+	qmd := out.(*QuiltModDescriptor)
+	if err := json.Unmarshal(data, qmd); err != nil || qmd.Loader.ID != "" {
+		return err
+	}
+
+	var fmd FabricModDescriptor
+	if err := json.Unmarshal(data, &fmd); err != nil {
+		return err
+	}
+
+	qmd.Loader.ID = fmd.ID
+	qmd.Loader.Version = fmd.Version
+	qmd.Loader.Metadata.Name = fmd.Name
+	qmd.Loader.Metadata.Description = fmd.Description
+	qmd.Loader.Metadata.Contact = fmd.Contact
+
+	qmd.Loader.Metadata.Contributors = make(map[string]string, len(fmd.Authors))
+	for _, a := range fmd.GetAuthors() {
+		qmd.Loader.Metadata.Contributors[a] = "Author"
+	}
+
+	for _, d := range fmd.GetDependencies() {
+		qmd.Loader.Depends = append(qmd.Loader.Depends, QuiltDependency{ID: d.Name, Optional: !d.Required})
+	}
+
+	return nil
+}
+
+func (d *QuiltDependency) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &d.ID); err == nil {
+		return nil
+	}
+
+	type Alias QuiltDependency
+	return json.Unmarshal(data, (*Alias)(d))
+}
+
+func (md QuiltModDescriptor) GetName() string {
+	if md.Loader.Metadata.Name != "" {
+		return md.Loader.Metadata.Name
+	}
+
+	return md.Loader.ID
+}
+
+func (md QuiltModDescriptor) GetIdentifier() string {
+	return md.Loader.ID
+}
+
+func (md QuiltModDescriptor) GetVersion() string {
+	return md.Loader.Version
+}
+
+func (md QuiltModDescriptor) GetAuthors() []string {
+	authors := make([]string, 0, len(md.Loader.Metadata.Contributors))
+	for name := range md.Loader.Metadata.Contributors {
+		authors = append(authors, name)
+	}
+
+	slices.Sort(authors)
+	return authors
+}
+
+func (md QuiltModDescriptor) GetDescription() string {
+	return md.Loader.Metadata.Description
+}
+
+func (md QuiltModDescriptor) GetWebsite() string {
+	return md.Loader.Metadata.Contact.Homepage
+}
+
+func (md QuiltModDescriptor) GetSourceURL() string {
+	return md.Loader.Metadata.Contact.Sources
+}
+
+func (md QuiltModDescriptor) GetDependencies() []bucket.Dependency {
+	deps := make([]bucket.Dependency, 0, len(md.Loader.Depends))
+	for _, d := range md.Loader.Depends {
+		if !slices.Contains(loaderDependencies, d.ID) {
+			deps = append(deps, bucket.Dependency{Name: d.ID, Required: !d.Optional})
+		}
+	}
+
+	return deps
+}
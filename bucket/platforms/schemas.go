@@ -0,0 +1,89 @@
+package platforms
+
+import "github.com/MRtecno98/bucket/bucket"
+
+// Known keys of the server config files, sections with keys that change
+// between versions or hold user defined names are allowed as a whole
+
+var ServerPropertiesSchema = bucket.ServerSchema{
+	"accepts-transfers", "allow-flight", "allow-nether", "broadcast-console-to-ops",
+	"broadcast-rcon-to-ops", "bug-report-link", "difficulty", "enable-command-block",
+	"enable-jmx-monitoring", "enable-query", "enable-rcon", "enable-status",
+	"enforce-secure-profile", "enforce-whitelist", "entity-broadcast-range-percentage",
+	"force-gamemode", "function-permission-level", "gamemode", "generate-structures",
+	"generator-settings", "hardcore", "hide-online-players", "initial-disabled-packs",
+	"initial-enabled-packs", "level-name", "level-seed", "level-type", "log-ips",
+	"max-chained-neighbor-updates", "max-players", "max-tick-time", "max-world-size",
+	"motd", "network-compression-threshold", "online-mode", "op-permission-level",
+	"pause-when-empty-seconds", "player-idle-timeout", "prevent-proxy-connections",
+	"previews-chat", "pvp", "query.port", "rate-limit", "rcon.password", "rcon.port",
+	"region-file-compression", "require-resource-pack", "resource-pack", "resource-pack-id",
+	"resource-pack-prompt", "resource-pack-sha1", "server-ip", "server-port",
+	"simulation-distance", "spawn-animals", "spawn-monsters", "spawn-npcs",
+	"spawn-protection", "sync-chunk-writes", "text-filtering-config", "text-filtering-version",
+	"use-native-transport", "view-distance", "white-list",
+}
+
+var BukkitSchema = bucket.ServerSchema{
+	"settings.allow-end", "settings.warn-on-overload", "settings.permissions-file",
+	"settings.update-folder", "settings.plugin-profiling", "settings.connection-throttle",
+	"settings.query-plugins", "settings.deprecated-verbose", "settings.shutdown-message",
+	"settings.minimum-api", "settings.use-map-color-cache",
+	"spawn-limits.*", "chunk-gc.*", "ticks-per.*", "aliases", "worlds.*",
+}
+
+var SpigotSchema = bucket.ServerSchema{
+	"config-version", "settings.debug", "settings.sample-count", "settings.bungeecord",
+	"settings.player-shuffle", "settings.user-cache-size", "settings.save-user-cache-on-stop-only",
+	"settings.moved-wrongly-threshold", "settings.moved-too-quickly-multiplier",
+	"settings.timeout-time", "settings.restart-on-crash", "settings.restart-script",
+	"settings.netty-threads", "settings.log-villager-deaths", "settings.log-named-deaths",
+	"settings.attribute.*", "messages.*", "advancements.*", "commands.*", "players.*",
+	"stats.*", "world-settings.*",
+}
+
+var PaperGlobalSchema = bucket.ServerSchema{
+	"_version", "anticheat.*", "block-updates.*", "chunk-loading-advanced.*",
+	"chunk-loading-basic.*", "chunk-system.*", "collisions.*", "commands.*", "console.*",
+	"item-validation.*", "logging.*", "messages.*", "misc.*", "packet-limiter.*",
+	"player-auto-save.*", "proxies.*", "scoreboards.*", "spam-limiter.*", "spark.*",
+	"timings.*", "unsupported-settings.*", "watchdog.*",
+}
+
+var PaperWorldSchema = bucket.ServerSchema{
+	"_version", "anticheat.*", "chunks.*", "collisions.*", "command-blocks.*", "entities.*",
+	"environment.*", "feature-seeds.*", "fishing-time-range.*", "fixes.*", "hopper.*",
+	"lootables.*", "maps.*", "max-growth-height.*", "misc.*", "scoreboards.*", "spawn.*",
+	"tick-rates.*", "unsupported-settings.*",
+}
+
+// Before 1.19 paper had a single config file
+var LegacyPaperSchema = bucket.ServerSchema{
+	"config-version", "verbose", "settings.*", "messages.*", "timings.*", "world-settings.*",
+}
+
+var PurpurSchema = bucket.ServerSchema{
+	"config-version", "verbose", "settings.*", "world-settings.*",
+}
+
+var PufferfishSchema = bucket.ServerSchema{
+	"config-version", "info.*", "max-loads-per-projectile", "dab.*", "enable-books",
+	"enable-suffocation-optimization", "inactive-goal-selector-throttle", "misc.*",
+	"projectile.*", "sentry-dsn", "enable-async-mob-spawning", "enable-async-entity-tracker",
+	"enable-async-pathfinding", "tps-catchup", "web-services.*", "flare.*",
+}
+
+var BungeeSchema = bucket.ServerSchema{
+	"listeners", "servers.*", "groups.*", "permissions.*", "ip_forward", "online_mode",
+	"player_limit", "prevent_proxy_connections", "timeout", "connection_throttle",
+	"connection_throttle_limit", "network_compression_threshold", "log_commands", "log_pings",
+	"stats", "forge_support", "disabled_commands", "remote_ping_cache", "remote_ping_timeout",
+	"server_connect_timeout", "enforce_secure_profile", "max_packets_per_second",
+	"max_packets_data_per_second",
+}
+
+var WaterfallSchema = bucket.ServerSchema{
+	"use_netty_dns_resolver", "throttling.*", "disable_entity_metadata_rewrite",
+	"disable_tab_list_rewrite", "log_initial_handler_connections", "game_version",
+	"disable_modern_tab_limiter",
+}
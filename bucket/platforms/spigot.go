@@ -2,9 +2,9 @@
 package platforms
 
 import (
+	"fmt"
 	"log"
 	"slices"
-	"strings"
 
 	"github.com/MRtecno98/bucket/bucket"
 	"gopkg.in/yaml.v2"
@@ -26,6 +26,8 @@ type SpigotPluginDescriptor struct {
 	Prefix      string   `yaml:"prefix"`
 	Libraries   []string `yaml:"libraries"`
 
+	FoliaSupported bool `yaml:"folia-supported"`
+
 	Commands map[string]struct {
 		Description       string   `yaml:"description"`
 		Aliases           []string `yaml:"aliases"`
@@ -41,13 +43,23 @@ type SpigotPluginDescriptor struct {
 	} `yaml:"permissions"`
 }
 
+const (
+	CraftBukkitMain   = "org.bukkit.craftbukkit.Main"
+	SpigotBundlerMain = "org.bukkit.craftbukkit.bootstrap.Main"
+)
+
 var SpigotTypePlatform = bucket.PlatformType{
 	Name: "spigot",
 	// There's actually not a bukkit platform, but there may be
 	// other derivatives of it other than spigot
 	Compatible: []string{"bukkit"},
-	Install:    InstallSpigot,
-	Detect:     DetectSpigot,
+	ServerConfigs: map[string]bucket.ServerSchema{
+		"server.properties": ServerPropertiesSchema,
+		"bukkit.yml":        BukkitSchema,
+		"spigot.yml":        SpigotSchema,
+	},
+	Install: InstallSpigot,
+	Detect:  DetectSpigot,
 	Build: func(context *bucket.OpenContext) bucket.Platform {
 		return NewSpigotPlatform(context) // Go boilerplate
 	},
@@ -93,8 +105,9 @@ func NewSpigotPlatform(context *bucket.OpenContext) *SpigotPlatform {
 }
 
 func DetectSpigot(context *bucket.OpenContext) (bucket.Platform, error) {
-	res, err := bucket.DetectJarPathDirs([]string{"", "bundler/versions"}, context, func(path string) bool {
-		return strings.Contains(path, "org\\spigotmc")
+	res, err := bucket.DetectServerJar(context, func(jar *bucket.ServerJar) bool {
+		return jar.Launcher(CraftBukkitMain, SpigotBundlerMain) ||
+			jar.Embeds("spigot") || jar.Contains("org/spigotmc/")
 	})
 
 	if err != nil {
@@ -108,8 +121,9 @@ func DetectSpigot(context *bucket.OpenContext) (bucket.Platform, error) {
 	}
 }
 
-func InstallSpigot(context *bucket.OpenContext) error {
-	return nil
+func InstallSpigot(context *bucket.OpenContext, version string) error {
+	// Spigot doesn't distribute server jars
+	return fmt.Errorf("spigot: %w, build the server jar with BuildTools", bucket.ErrInstallUnsupported)
 }
 
 func (pl SpigotPluginDescriptor) GetName() string {
@@ -137,3 +151,15 @@ func (pl SpigotPluginDescriptor) GetDescription() string {
 func (pl SpigotPluginDescriptor) GetWebsite() string {
 	return pl.Website
 }
+
+func (pl SpigotPluginDescriptor) GetMainClass() string {
+	return pl.MainClass
+}
+
+func (pl SpigotPluginDescriptor) DeclaredPlatforms() []string {
+	if pl.FoliaSupported {
+		return []string{FoliaTypePlatform.Name}
+	}
+
+	return nil
+}
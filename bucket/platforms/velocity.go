@@ -0,0 +1,119 @@
+package platforms
+
+import (
+	"log"
+
+	"github.com/MRtecno98/bucket/bucket"
+)
+
+type VelocityPluginDescriptor struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Version     string   `json:"version"`
+	Description string   `json:"description"`
+	URL         string   `json:"url"`
+	Authors     []string `json:"authors"`
+	MainClass   string   `json:"main"`
+
+	Dependencies []struct {
+		ID       string `json:"id"`
+		Optional bool   `json:"optional"`
+	} `json:"dependencies"`
+}
+
+const VelocityMain = "com.velocitypowered.proxy.Velocity"
+
+var VelocityTypePlatform = bucket.PlatformType{
+	Name:    "velocity",
+	Install: InstallVelocity,
+	Detect:  DetectVelocity,
+	Build: func(context *bucket.OpenContext) bucket.Platform {
+		return NewVelocityPlatform(context) // Go boilerplate
+	},
+}
+
+func init() {
+	bucket.RegisterPlatform(VelocityTypePlatform, 1)
+}
+
+type VelocityPlatform struct {
+	bucket.PluginCachePlatform
+}
+
+func (p *VelocityPlatform) Type() bucket.PlatformType {
+	return VelocityTypePlatform
+}
+
+func NewVelocityPlatform(context *bucket.OpenContext) *VelocityPlatform {
+	return &VelocityPlatform{
+		PluginCachePlatform: bucket.PluginCachePlatform{
+			PluginProvider: bucket.JarPluginPlatform[VelocityPluginDescriptor]{
+				ContextPlatform: bucket.ContextPlatform{Context: context},
+				Decode:          bucket.JSONDecode,
+				PluginFiles:     []string{"velocity-plugin.json"},
+				PluginFolder:    "plugins",
+			},
+		},
+	}
+}
+
+func DetectVelocity(context *bucket.OpenContext) (bucket.Platform, error) {
+	res, err := bucket.DetectServerJar(context, func(jar *bucket.ServerJar) bool {
+		return jar.Launcher(VelocityMain) || jar.Contains("com/velocitypowered/proxy/")
+	})
+
+	if err != nil {
+		log.Println("Error during platform check:", err)
+	}
+
+	if res {
+		return NewVelocityPlatform(context), nil
+	} else {
+		return nil, nil
+	}
+}
+
+func InstallVelocity(context *bucket.OpenContext, version string) error {
+	return installPaperProject(context, "velocity", version)
+}
+
+func (pl VelocityPluginDescriptor) GetName() string {
+	if pl.Name != "" {
+		return pl.Name
+	}
+
+	return pl.ID
+}
+
+func (pl VelocityPluginDescriptor) GetIdentifier() string {
+	return pl.ID
+}
+
+func (pl VelocityPluginDescriptor) GetVersion() string {
+	return pl.Version
+}
+
+func (pl VelocityPluginDescriptor) GetAuthors() []string {
+	return pl.Authors
+}
+
+func (pl VelocityPluginDescriptor) GetDescription() string {
+	return pl.Description
+}
+
+func (pl VelocityPluginDescriptor) GetWebsite() string {
+	return pl.URL
+}
+
+func (pl VelocityPluginDescriptor) GetMainClass() string {
+	return pl.MainClass
+}
+
+func (pl VelocityPluginDescriptor) GetDependencies() []bucket.Dependency {
+	deps := make([]bucket.Dependency, 0, len(pl.Dependencies))
+	for _, dep := range pl.Dependencies {
+		deps = append(deps, bucket.Dependency{Name: dep.ID, Required: !dep.Optional})
+	}
+
+	return deps
+}
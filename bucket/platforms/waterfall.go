@@ -0,0 +1,56 @@
+package platforms
+
+import (
+	"log"
+
+	"github.com/MRtecno98/bucket/bucket"
+)
+
+var WaterfallTypePlatform = bucket.PlatformType{
+	Name:       "waterfall",
+	Compatible: []string{"bungeecoord"},
+	ServerConfigs: map[string]bucket.ServerSchema{
+		"waterfall.yml": WaterfallSchema,
+	},
+	Install: InstallWaterfall,
+	Detect:  DetectWaterfall,
+	Build: func(context *bucket.OpenContext) bucket.Platform {
+		return NewWaterfallPlatform(context) // Go boilerplate
+	},
+}
+
+func init() {
+	bucket.RegisterPlatform(WaterfallTypePlatform, 3)
+}
+
+type WaterfallPlatform struct {
+	BungeePlatform
+}
+
+func (p *WaterfallPlatform) Type() bucket.PlatformType {
+	return WaterfallTypePlatform
+}
+
+func NewWaterfallPlatform(context *bucket.OpenContext) *WaterfallPlatform {
+	return &WaterfallPlatform{*NewBungeePlatform(context)}
+}
+
+func DetectWaterfall(context *bucket.OpenContext) (bucket.Platform, error) {
+	res, err := bucket.DetectServerJar(context, func(jar *bucket.ServerJar) bool {
+		return jar.Launcher(BungeeMain) && jar.Contains("io/github/waterfallmc/")
+	})
+
+	if err != nil {
+		log.Println("error during platform check:", err)
+	}
+
+	if res {
+		return NewWaterfallPlatform(context), nil
+	} else {
+		return nil, nil
+	}
+}
+
+func InstallWaterfall(context *bucket.OpenContext, version string) error {
+	return installPaperProject(context, "waterfall", version)
+}
@@ -27,6 +27,10 @@ type LocalPlugin struct {
 	File afero.File
 }
 
+func (p *LocalPlugin) Hash() (string, error) {
+	return HashFile(p.File)
+}
+
 type Dependency struct {
 	Name     string
 	Required bool
@@ -42,3 +46,15 @@ type PluginMetadata interface {
 	GetDescription() string
 	GetWebsite() string
 }
+
+type MainClassDescriptor interface {
+	GetMainClass() string
+}
+
+type SourceLinked interface {
+	GetSourceURL() string
+}
+
+type Popular interface {
+	GetDownloads() int
+}
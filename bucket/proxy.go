@@ -0,0 +1,65 @@
+package bucket
+
+import (
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+)
+
+const DefaultProxyAddress = ":8080"
+
+// Upstream of every proxy route, a repository mirror URL
+// pointing to the proxy must include the route prefix
+// (e.g. http://proxy:8080/modrinth)
+var ProxyUpstreams = map[string]string{
+	"/modrinth/":     "https://api.modrinth.com",
+	"/modrinth/cdn/": "https://cdn.modrinth.com",
+	"/spiget/":       "https://api.spiget.org",
+}
+
+// MirrorURL joins the mirror base URL with the path of an upstream URL
+func MirrorURL(mirror, upstream string) (string, error) {
+	u, err := url.Parse(upstream)
+	if err != nil {
+		return "", err
+	}
+
+	m, err := url.Parse(strings.TrimSuffix(mirror, "/"))
+	if err != nil {
+		return "", err
+	}
+
+	m.Path += u.Path
+	m.RawQuery = u.RawQuery
+
+	return m.String(), nil
+}
+
+// NewProxyHandler forwards the requests on every route to its upstream
+// through the given transport, usually the shared caching transport
+func NewProxyHandler(transport http.RoundTripper) (http.Handler, error) {
+	mux := http.NewServeMux()
+
+	for prefix, upstream := range ProxyUpstreams {
+		target, err := url.Parse(upstream)
+		if err != nil {
+			return nil, err
+		}
+
+		mux.Handle(prefix, http.StripPrefix(strings.TrimSuffix(prefix, "/"), &httputil.ReverseProxy{
+			Transport: transport,
+			Rewrite: func(r *httputil.ProxyRequest) {
+				r.SetURL(target)
+				r.Out.Header.Del("Cookie")
+
+				if DEBUG {
+					log.Printf("proxy: %s %s\n", r.Out.Method, r.Out.URL)
+				}
+			},
+		}))
+	}
+
+	return mux, nil
+}
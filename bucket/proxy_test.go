@@ -0,0 +1,53 @@
+package bucket
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMirrorURL(t *testing.T) {
+	for _, c := range []struct{ mirror, upstream, want string }{
+		{"http://proxy:8080/modrinth", "https://api.modrinth.com/v2", "http://proxy:8080/modrinth/v2"},
+		{"http://proxy:8080/spiget/", "https://api.spiget.org/v2/", "http://proxy:8080/spiget/v2/"},
+		{"http://proxy/modrinth/cdn", "https://cdn.modrinth.com/data/a/b.jar?x=1", "http://proxy/modrinth/cdn/data/a/b.jar?x=1"},
+	} {
+		if got, err := MirrorURL(c.mirror, c.upstream); err != nil || got != c.want {
+			t.Errorf("MirrorURL(%s, %s) = %s, %v; want %s", c.mirror, c.upstream, got, err, c.want)
+		}
+	}
+}
+
+func TestProxyHandler(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.Path)
+	}))
+
+	defer upstream.Close()
+
+	saved := ProxyUpstreams
+	defer func() { ProxyUpstreams = saved }()
+
+	ProxyUpstreams = map[string]string{"/modrinth/": upstream.URL}
+
+	handler, err := NewProxyHandler(http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proxy := httptest.NewServer(handler)
+	defer proxy.Close()
+
+	res, err := http.Get(proxy.URL + "/modrinth/v2/project/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != "/v2/project/test" {
+		t.Fatalf("request forwarded to %q", body)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"hash"
 	"io"
+	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
@@ -22,6 +23,7 @@ import (
 // TODO: Modrinth repository format (https://modrinth.com/api/docs)
 
 const ModrinthEndpoint = "https://api.modrinth.com/v2"
+const ModrinthCDN = "cdn.modrinth.com"
 
 const ModrinthRepository = "modrinth"
 
@@ -80,6 +82,7 @@ type ModrinthProject struct {
 	Updated       string       `json:"updated"`
 	Followers     int          `json:"followers"`
 	Versions      []string     `json:"versions"`
+	Loaders       []string     `json:"loaders"`
 	Gallery       []struct {
 		URL         string `json:"url"`
 		RawURL      string `json:"raw_url"`
@@ -193,26 +196,74 @@ type ModrinthSummary struct {
 	Total  int `json:"total_hits"`
 }
 
+// Platforms named differently in the Modrinth loaders
+var ModrinthLoaderNames = map[string]string{
+	"bungeecoord": "bungeecord",
+}
+
+// ModrinthLoaders lists the Modrinth loaders of every platform compatible with the given one
+func ModrinthLoaders(platform bucket.PlatformType) []string {
+	loaders := platform.EveryCompatible()
+	for i, v := range loaders {
+		if name, ok := ModrinthLoaderNames[v]; ok {
+			loaders[i] = name
+		}
+	}
+
+	return loaders
+}
+
 type Modrinth struct {
 	bucket.HTTPRepository
 	bucket.LockRepository
 
 	Context *bucket.OpenContext
+
+	// Optional base URL replacing the API and CDN hosts
+	mirror string
 }
 
 func init() {
 	bucket.RegisterRepository(ModrinthRepository,
-		func(ctx context.Context, oc *bucket.OpenContext, opts map[string]string) bucket.Repository {
-			return NewModrinthRepository(ctx, oc) // Go boilerplate
+		func(ctx context.Context, oc *bucket.OpenContext, opts map[string]string) (bucket.Repository, error) {
+			return NewModrinthRepository(ctx, oc, opts) // Go boilerplate
 		})
 }
 
-func NewModrinthRepository(lock context.Context, context *bucket.OpenContext) *Modrinth {
+func NewModrinthRepository(lock context.Context, context *bucket.OpenContext, opts map[string]string) (*Modrinth, error) {
+	endpoint := ModrinthEndpoint
+	if opts["mirror"] != "" {
+		var err error
+		if endpoint, err = bucket.MirrorURL(opts["mirror"], ModrinthEndpoint); err != nil {
+			return nil, fmt.Errorf("invalid mirror: %w", err)
+		}
+	}
+
+	if err := bucket.SharedRetryTransport().ConfigureHost(endpoint, opts); err != nil {
+		return nil, err
+	}
+
 	return &Modrinth{
-		HTTPRepository: *bucket.NewHTTPRepository(ModrinthEndpoint),
+		HTTPRepository: *bucket.NewHTTPRepository(endpoint),
 		LockRepository: bucket.LockRepository{Lock: lock},
 		Context:        context,
+		mirror:         opts["mirror"],
+	}, nil
+}
+
+// downloadURL redirects CDN downloads to the mirror, the files
+// are served under the "/cdn" path of the mirror
+func (r *Modrinth) downloadURL(file string) (string, error) {
+	if r.mirror == "" {
+		return file, nil
+	}
+
+	u, err := url.Parse(file)
+	if err != nil || u.Host != ModrinthCDN {
+		return file, err
 	}
+
+	return bucket.MirrorURL(strings.TrimSuffix(r.mirror, "/")+"/cdn", file)
 }
 
 func (r *Modrinth) Provider() string {
@@ -223,18 +274,27 @@ func (r *Modrinth) PluginType() reflect.Type {
 	return reflect.TypeOf(ModrinthProjectSummary{})
 }
 
-func (r *Modrinth) makreq() *resty.Request {
-	return r.HTTPClient.R().SetContext(r.Lock)
+// gameVersion is the Minecraft version of the context, empty if unknown
+func (r *Modrinth) gameVersion() string {
+	if r == nil || r.Context == nil || r.Context.Platform == nil {
+		return ""
+	}
+
+	return r.Context.Platform.GameVersion()
+}
+
+func (r *Modrinth) makreq(ctx context.Context) *resty.Request {
+	return r.HTTPClient.R().SetContext(ctx)
 }
 
-func (r *Modrinth) Resolve(plugin bucket.Plugin) (bucket.RemotePlugin, []bucket.RemotePlugin, error) {
+func (r *Modrinth) Resolve(ctx context.Context, plugin bucket.Plugin) (bucket.RemotePlugin, []bucket.RemotePlugin, error) {
 	if loc, ok := plugin.(bucket.LocalPlugin); ok {
 		h := sha1.New()
 		if _, err := io.Copy(h, loc.File); err != nil {
 			return nil, nil, err
 		}
 
-		ver, err := r.GetByHash(hex.EncodeToString(h.Sum(nil)))
+		ver, err := r.GetByHash(ctx, hex.EncodeToString(h.Sum(nil)))
 		if err == nil {
 			res := ver.(*ModrinthVersion).ModrinthProject
 			return &res, []bucket.RemotePlugin{&res}, nil
@@ -245,7 +305,7 @@ func (r *Modrinth) Resolve(plugin bucket.Plugin) (bucket.RemotePlugin, []bucket.
 	var res []bucket.RemotePlugin
 	for _, name := range bucket.Distinct([]string{
 		plugin.GetName(), bucket.Decamel(plugin.GetName(), " ")}) {
-		cand, n, err := r.Search(name, 5)
+		cand, n, err := r.Search(ctx, name, 5)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -255,16 +315,16 @@ func (r *Modrinth) Resolve(plugin bucket.Plugin) (bucket.RemotePlugin, []bucket.
 	}
 
 	if tot == 0 {
-		return nil, nil, r.parseError(fmt.Errorf("no match found for \"%s\"", plugin.GetName()))
+		return nil, nil, r.parseError(fmt.Errorf("%w for \"%s\"", bucket.ErrNoMatch, plugin.GetName()))
 	}
 
 	return res[0], res, nil
 }
 
-func (r *Modrinth) Get(identifier string) (bucket.RemotePlugin, error) {
+func (r *Modrinth) Get(ctx context.Context, identifier string) (bucket.RemotePlugin, error) {
 	var plugin ModrinthProject
 
-	res, err := r.makreq().SetResult(&plugin).Get("/project/" + identifier)
+	res, err := r.makreq(ctx).SetResult(&plugin).Get("/project/" + identifier)
 	if err != nil {
 		return nil, err
 	}
@@ -276,13 +336,13 @@ func (r *Modrinth) Get(identifier string) (bucket.RemotePlugin, error) {
 	proj := res.Result().(*ModrinthProject)
 	proj.repository = r
 
-	return proj, proj.requestMembers()
+	return proj, proj.requestMembers(ctx)
 }
 
-func (r *Modrinth) GetByHash(sha1 string) (bucket.RemoteVersion, error) {
+func (r *Modrinth) GetByHash(ctx context.Context, sha1 string) (bucket.RemoteVersion, error) {
 	var plugin ModrinthVersion
 
-	res, err := r.makreq().SetResult(&plugin).Get("/version_file/" + sha1)
+	res, err := r.makreq(ctx).SetResult(&plugin).Get("/version_file/" + sha1)
 	if err != nil {
 		return nil, err
 	}
@@ -293,7 +353,7 @@ func (r *Modrinth) GetByHash(sha1 string) (bucket.RemoteVersion, error) {
 
 	ver := res.Result().(*ModrinthVersion)
 
-	prj, err := r.Get(ver.ProjectID)
+	prj, err := r.Get(ctx, ver.ProjectID)
 	if err != nil {
 		return nil, fmt.Errorf("%v: version file found but associated project is unavailable", err)
 	}
@@ -303,14 +363,14 @@ func (r *Modrinth) GetByHash(sha1 string) (bucket.RemoteVersion, error) {
 	return ver, nil
 }
 
-func (r *Modrinth) search(options map[string]string, max int) ([]bucket.RemotePlugin, int, error) {
+func (r *Modrinth) search(ctx context.Context, options map[string]string, max int) ([]bucket.RemotePlugin, int, error) {
 	var result ModrinthSummary
 
 	if max > 0 {
 		options["limit"] = strconv.Itoa(max)
 	}
 
-	res, err := r.makreq().
+	res, err := r.makreq(ctx).
 		SetQueryParams(options).
 		SetResult(&result).
 		Get("/search")
@@ -334,27 +394,54 @@ func (r *Modrinth) search(options map[string]string, max int) ([]bucket.RemotePl
 	return versions, summary.Total, nil
 }
 
-func (r *Modrinth) SearchAll(query string, max int) ([]bucket.RemotePlugin, int, error) {
-	return r.search(map[string]string{
+func (r *Modrinth) SearchAll(ctx context.Context, query string, max int) ([]bucket.RemotePlugin, int, error) {
+	return r.search(ctx, map[string]string{
 		"query": query,
 	}, max)
 }
 
-func (r *Modrinth) Search(query string, max int) ([]bucket.RemotePlugin, int, error) {
+func (r *Modrinth) Search(ctx context.Context, query string, max int) ([]bucket.RemotePlugin, int, error) {
 	qmap := map[string]string{
 		"query": query,
 	}
 
 	if r.Context.Platform != nil {
-		loaders := r.Context.Platform.Type().EveryCompatible()
-		for i, v := range loaders {
-			loaders[i] = fmt.Sprintf("\"categories:%s\"", v)
-		}
+		qmap["facets"] = ModrinthFacets(r.Context.Platform.Type())
+	}
+
+	return r.search(ctx, qmap, max)
+}
+
+// ModrinthFacets filters the search results by the platform loaders,
+// mod loaders also need mods that can run on the server
+func ModrinthFacets(platform bucket.PlatformType) string {
+	loaders := ModrinthLoaders(platform)
+	if platform.Strict {
+		loaders = []string{platform.Name}
+	}
+
+	facets := [][]string{prefixFacets("categories", loaders...)}
 
-		qmap["facets"] = fmt.Sprintf("[[%s]]", strings.Join(loaders, ", "))
+	if platform.Mods {
+		facets = append(facets, prefixFacets("project_type", string(ProjectMod)),
+			prefixFacets("server_side", string(SideRequired), string(SideOptional)))
 	}
 
-	return r.search(qmap, max)
+	groups := make([]string, len(facets))
+	for i, f := range facets {
+		groups[i] = "[" + strings.Join(f, ", ") + "]"
+	}
+
+	return "[" + strings.Join(groups, ", ") + "]"
+}
+
+func prefixFacets(facet string, values ...string) []string {
+	res := make([]string, len(values))
+	for i, v := range values {
+		res[i] = fmt.Sprintf("\"%s:%s\"", facet, v)
+	}
+
+	return res
 }
 
 func (r *Modrinth) parseReqError(res *resty.Response) error {
@@ -365,7 +452,7 @@ func (r *Modrinth) parseReqError(res *resty.Response) error {
 }
 
 func (r *Modrinth) parseError(err error) error {
-	return fmt.Errorf("modrinth: %s", err)
+	return fmt.Errorf("modrinth: %w", err)
 }
 
 func (s *ModrinthProjectSummary) UnmarshalJSON(data []byte) error {
@@ -375,7 +462,6 @@ func (s *ModrinthProjectSummary) UnmarshalJSON(data []byte) error {
 	}
 
 	s.ModrinthProject.ID = s.ID
-	s.ModrinthProject.Versions = s.GameVersions
 	s.ModrinthProject.Followers = s.Followers
 	s.ModrinthProject.Published = s.Created
 	s.ModrinthProject.Updated = s.Updated
@@ -392,10 +478,10 @@ func (s *ModrinthProjectSummary) GetAuthors() []string {
 	return []string{s.Author}
 }
 
-func (r *Modrinth) GetVersionByID(identifier string) (bucket.RemoteVersion, error) {
+func (r *Modrinth) GetVersionByID(ctx context.Context, identifier string) (bucket.RemoteVersion, error) {
 	var version ModrinthVersion
 
-	res, err := r.makreq().SetResult(&version).Get("/project/version/" + identifier)
+	res, err := r.makreq(ctx).SetResult(&version).Get("/project/version/" + identifier)
 	if err != nil {
 		return nil, r.parseError(err)
 	}
@@ -415,8 +501,8 @@ func (p *ModrinthProject) GetRepository() bucket.Repository {
 	return p.repository
 }
 
-func (p *ModrinthProject) GetLatestVersion() (bucket.RemoteVersion, error) {
-	vers, err := p.GetVersions(1)
+func (p *ModrinthProject) GetLatestVersion(ctx context.Context) (bucket.RemoteVersion, error) {
+	vers, err := p.GetVersions(ctx, 1)
 	if err != nil {
 		return nil, err
 	}
@@ -424,14 +510,32 @@ func (p *ModrinthProject) GetLatestVersion() (bucket.RemoteVersion, error) {
 	return vers[0], nil
 }
 
-func (p *ModrinthProject) GetVersionByID(identifier string) (bucket.RemoteVersion, error) {
-	return p.repository.GetVersionByID(identifier)
+func (p *ModrinthProject) GetVersionByID(ctx context.Context, identifier string) (bucket.RemoteVersion, error) {
+	ver, err := p.repository.GetVersionByID(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	mv := ver.(*ModrinthVersion)
+	if mv.ProjectID != p.ID {
+		return nil, p.repository.parseError(fmt.Errorf("version %s is not part of %s", identifier, p.Slug))
+	}
+
+	mv.ModrinthProject = *p
+	return mv, nil
+}
+
+func (p *ModrinthProject) GetVersions(ctx context.Context, limit int) ([]bucket.RemoteVersion, error) {
+	return p.getVersions(ctx, nil, limit)
 }
 
-func (p *ModrinthProject) GetVersions(limit int) ([]bucket.RemoteVersion, error) {
+// getVersions lists the project versions, filtered by the
+// loaders and game_versions query parameters if present
+func (p *ModrinthProject) getVersions(ctx context.Context, query map[string]string, limit int) ([]bucket.RemoteVersion, error) {
 	var versions []ModrinthVersion
 
-	res, err := p.repository.HTTPClient.R().SetResult(&versions).Get("/project/" + p.Slug + "/version")
+	res, err := p.repository.makreq(ctx).SetQueryParams(query).
+		SetResult(&versions).Get("/project/" + p.Slug + "/version")
 	if err != nil {
 		return nil, p.repository.parseError(err)
 	}
@@ -453,18 +557,41 @@ func (p *ModrinthProject) GetVersions(limit int) ([]bucket.RemoteVersion, error)
 	return remoteVersions, nil
 }
 
-func (p *ModrinthProject) GetVersionIdentifiers() ([]string, error) {
+func (p *ModrinthProject) GetVersionIdentifiers(ctx context.Context) ([]string, error) {
+	if len(p.Versions) > 0 {
+		return p.Versions, nil
+	}
+
+	// Search results don't include the version list
+	vers, err := p.GetVersions(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range vers {
+		p.Versions = append(p.Versions, v.(*ModrinthVersion).ID)
+	}
+
 	return p.Versions, nil
 }
 
-func (p *ModrinthProject) GetLatestCompatible(platform bucket.PlatformType) (bucket.RemoteVersion, error) {
-	versions, err := p.GetVersions(0)
+func (p *ModrinthProject) GetLatestCompatible(ctx context.Context, platform bucket.PlatformType) (bucket.RemoteVersion, error) {
+	return p.GetLatestCompatibleFor(ctx, platform, p.repository.gameVersion())
+}
+
+func (p *ModrinthProject) GetLatestCompatibleFor(ctx context.Context, platform bucket.PlatformType, game string) (bucket.RemoteVersion, error) {
+	var query map[string]string
+	if game != "" {
+		query = map[string]string{"game_versions": fmt.Sprintf("[\"%s\"]", game)}
+	}
+
+	versions, err := p.getVersions(ctx, query, 0)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, v := range versions {
-		if v.Compatible(platform) {
+		if v.(*ModrinthVersion).compatible(platform, game) {
 			return v, nil
 		}
 	}
@@ -472,8 +599,10 @@ func (p *ModrinthProject) GetLatestCompatible(platform bucket.PlatformType) (buc
 	return nil, p.repository.parseError(fmt.Errorf("no compatible version found"))
 }
 
+// Compatible only checks the loaders, so that installed plugins are identified
+// even when the project has no build for the exact Minecraft version
 func (p ModrinthProject) Compatible(platform bucket.PlatformType) bool {
-	ver, err := p.GetLatestCompatible(platform)
+	ver, err := p.GetLatestCompatibleFor(p.repository.Lock, platform, "")
 	return err == nil && ver != nil
 }
 
@@ -481,8 +610,8 @@ func (p *ModrinthProject) GetDependencies() []bucket.Dependency {
 	panic("not implemented") // TODO: Implement
 }
 
-func (p *ModrinthProject) requestMembers() error {
-	res, err := p.repository.makreq().
+func (p *ModrinthProject) requestMembers(ctx context.Context) error {
+	res, err := p.repository.makreq(ctx).
 		SetResult(&p.authors).
 		Get("/project/" + p.ID + "/members")
 	if err != nil {
@@ -498,7 +627,7 @@ func (p *ModrinthProject) requestMembers() error {
 
 func (p *ModrinthProject) GetAuthors() []string {
 	if len(p.authors) == 0 {
-		if err := p.requestMembers(); err != nil {
+		if err := p.requestMembers(p.repository.Lock); err != nil {
 			return []string{}
 		}
 	}
@@ -523,6 +652,23 @@ func (p *ModrinthProject) GetWebsite() string {
 	return p.WikiURL
 }
 
+func (p *ModrinthProject) GetSourceURL() string {
+	return p.SourceURL
+}
+
+func (p *ModrinthProject) GetDownloads() int {
+	return p.Downloads
+}
+
+// DeclaredPlatforms lists the loaders, which for search results are among the categories
+func (p *ModrinthProject) DeclaredPlatforms() []string {
+	return append(slices.Clone(p.Loaders), p.Categories...)
+}
+
+func (p *ModrinthVersion) DeclaredPlatforms() []string {
+	return p.Loaders
+}
+
 func (p *ModrinthVersion) GetVersion() string {
 	return p.VersionNumber
 }
@@ -531,12 +677,33 @@ func (p *ModrinthVersion) GetVersionName() string {
 	return p.Name
 }
 
+func (p *ModrinthVersion) GetVersionIdentifier() string {
+	return p.ID
+}
+
 func (p *ModrinthVersion) GetDependencies() []bucket.Dependency {
 	panic("not implemented") // TODO: Implement
 }
 
 func (p *ModrinthVersion) Compatible(platform bucket.PlatformType) bool {
-	comp := platform.EveryCompatible()
+	return p.compatible(platform, p.repository.gameVersion())
+}
+
+func (p *ModrinthVersion) compatible(platform bucket.PlatformType, game string) bool {
+	if !platform.Supports(p) {
+		return false
+	}
+
+	// Client only mods are useless on a server
+	if platform.Mods && p.ServerSide == SideUnsupported {
+		return false
+	}
+
+	if game != "" && len(p.GameVersions) > 0 && !slices.Contains(p.GameVersions, game) {
+		return false
+	}
+
+	comp := ModrinthLoaders(platform)
 	for _, v := range p.Loaders {
 		if slices.Contains(comp, v) {
 			return true
@@ -546,7 +713,7 @@ func (p *ModrinthVersion) Compatible(platform bucket.PlatformType) bool {
 	return false
 }
 
-func (p *ModrinthVersion) GetFiles() ([]bucket.RemoteFile, error) {
+func (p *ModrinthVersion) GetFiles(_ context.Context) ([]bucket.RemoteFile, error) {
 	var remoteFiles []bucket.RemoteFile
 	for i := range p.Files {
 		if p.Files[i].repository == nil {
@@ -567,11 +734,16 @@ func (f *ModrinthFile) Optional() bool {
 	return !f.Primary
 }
 
-func (f *ModrinthFile) Download() (io.ReadCloser, error) {
-	req := f.repository.HTTPClient.R()
+func (f *ModrinthFile) Download(ctx context.Context) (io.ReadCloser, error) {
+	req := f.repository.makreq(ctx)
 	req.SetDoNotParseResponse(true)
 
-	resp, err := req.Get(f.URL)
+	u, err := f.repository.downloadURL(f.URL)
+	if err != nil {
+		return nil, f.repository.parseError(err)
+	}
+
+	resp, err := req.Get(u)
 	if err != nil {
 		return nil, f.repository.parseError(err)
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
@@ -21,7 +22,8 @@ const SpigotMCRepository = "spigotmc"
 type SpigotMC struct {
 	bucket.LockRepository
 
-	Client *spiget.Client
+	Client  *spiget.Client
+	Context *bucket.OpenContext
 
 	categoryNames map[int]string
 }
@@ -53,16 +55,35 @@ type SpigotFile struct {
 
 func init() {
 	bucket.RegisterRepository(SpigotMCRepository,
-		func(ctx context.Context, oc *bucket.OpenContext, opts map[string]string) bucket.Repository {
-			return NewSpigotRepository(ctx, oc) // Go boilerplate
+		func(ctx context.Context, oc *bucket.OpenContext, opts map[string]string) (bucket.Repository, error) {
+			return NewSpigotRepository(ctx, oc, opts) // Go boilerplate
 		})
 }
 
-func NewSpigotRepository(ctx context.Context, context *bucket.OpenContext) *SpigotMC {
+func NewSpigotRepository(ctx context.Context, context *bucket.OpenContext, opts map[string]string) (*SpigotMC, error) {
+	client := spiget.NewClient(bucket.NewHTTPClient())
+	client.UserAgent = bucket.UserAgent
+
+	if opts["mirror"] != "" {
+		base, err := bucket.MirrorURL(opts["mirror"], client.BaseURL.String())
+		if err != nil {
+			return nil, fmt.Errorf("invalid mirror: %w", err)
+		}
+
+		if client.BaseURL, err = url.Parse(base); err != nil {
+			return nil, fmt.Errorf("invalid mirror: %w", err)
+		}
+	}
+
+	if err := bucket.SharedRetryTransport().ConfigureHost(client.BaseURL.String(), opts); err != nil {
+		return nil, err
+	}
+
 	return &SpigotMC{
 		LockRepository: bucket.LockRepository{Lock: ctx},
-		Client:         spiget.NewClient(nil),
-	}
+		Client:         client,
+		Context:        context,
+	}, nil
 }
 
 func (r *SpigotMC) Provider() string {
@@ -73,13 +94,13 @@ func (r *SpigotMC) PluginType() reflect.Type {
 	return reflect.TypeOf(SpigotResource{})
 }
 
-func (r *SpigotMC) Resolve(plugin bucket.Plugin) (bucket.RemotePlugin, []bucket.RemotePlugin, error) {
+func (r *SpigotMC) Resolve(ctx context.Context, plugin bucket.Plugin) (bucket.RemotePlugin, []bucket.RemotePlugin, error) {
 	var tot int
 	var res []bucket.RemotePlugin
 
 	for _, name := range bucket.Distinct([]string{
 		plugin.GetName(), bucket.Decamel(plugin.GetName(), " ")}) {
-		cand, n, err := r.Search(name, 5)
+		cand, n, err := r.Search(ctx, name, 5)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -90,14 +111,14 @@ func (r *SpigotMC) Resolve(plugin bucket.Plugin) (bucket.RemotePlugin, []bucket.
 
 	if meta, ok := plugin.(bucket.PluginMetadata); ok {
 		for _, a := range meta.GetAuthors() {
-			auts, err := r.GetAuthor(strings.ReplaceAll(a, " ", ""))
+			auts, err := r.GetAuthor(ctx, strings.ReplaceAll(a, " ", ""))
 			if err != nil {
 				continue
 			}
 
 			for _, aut := range auts {
-				autres, rsp, err := r.GetAuthorResources(aut)
-				if rsp.StatusCode == 404 {
+				autres, rsp, err := r.GetAuthorResources(ctx, aut)
+				if rsp != nil && rsp.StatusCode == 404 {
 					continue
 				} else if err != nil {
 					return nil, nil, err
@@ -112,32 +133,32 @@ func (r *SpigotMC) Resolve(plugin bucket.Plugin) (bucket.RemotePlugin, []bucket.
 	}
 
 	if tot == 0 {
-		return nil, nil, r.parseError(fmt.Errorf("no match found for \"%s\"", plugin.GetName()))
+		return nil, nil, r.parseError(fmt.Errorf("%w for \"%s\"", bucket.ErrNoMatch, plugin.GetName()))
 	}
 
 	return res[0], res, nil
 }
 
-func (r *SpigotMC) Get(identifier string) (bucket.RemotePlugin, error) {
+func (r *SpigotMC) Get(ctx context.Context, identifier string) (bucket.RemotePlugin, error) {
 	i, err := strconv.Atoi(identifier)
 	if err != nil {
 		return nil, r.parseError(err)
 	}
 
-	res, _, err := r.Client.Resources.Get(r.Lock, i)
+	res, _, err := r.Client.Resources.Get(ctx, i)
 
 	return &SpigotResource{repository: r, Resource: *res}, r.parseError(err)
 }
 
-func (r *SpigotMC) SearchAll(query string, max int) ([]bucket.RemotePlugin, int, error) {
-	return r.Search(query, max)
+func (r *SpigotMC) SearchAll(ctx context.Context, query string, max int) ([]bucket.RemotePlugin, int, error) {
+	return r.Search(ctx, query, max)
 }
 
-func (r *SpigotMC) Search(query string, max int) ([]bucket.RemotePlugin, int, error) {
-	res, rsp, err := r.Client.Search.SearchResource(r.Lock, query,
+func (r *SpigotMC) Search(ctx context.Context, query string, max int) ([]bucket.RemotePlugin, int, error) {
+	res, rsp, err := r.Client.Search.SearchResource(ctx, query,
 		&spiget.ResourceSearchOptions{})
 
-	if rsp.StatusCode == 404 {
+	if rsp != nil && rsp.StatusCode == 404 {
 		return []bucket.RemotePlugin{}, 0, nil
 	} else if err != nil {
 		return nil, 0, r.parseError(err)
@@ -167,16 +188,16 @@ func (r *SpigotMC) InitCategoryNames() error {
 	return nil
 }
 
-func (r *SpigotMC) GetAuthor(name string) ([]*spiget.Author, error) {
-	auths, rsp, err := r.Client.Authors.Search(r.Lock, name, &spiget.AuthorSearchOptions{})
-	if rsp.StatusCode == 404 {
+func (r *SpigotMC) GetAuthor(ctx context.Context, name string) ([]*spiget.Author, error) {
+	auths, rsp, err := r.Client.Authors.Search(ctx, name, &spiget.AuthorSearchOptions{})
+	if rsp != nil && rsp.StatusCode == 404 {
 		return []*spiget.Author{}, nil
 	}
 
 	return auths, err
 }
 
-func (r *SpigotMC) GetAuthorResources(author *spiget.Author) ([]*SpigotResource, *spiget.Response, error) {
+func (r *SpigotMC) GetAuthorResources(ctx context.Context, author *spiget.Author) ([]*SpigotResource, *spiget.Response, error) {
 	url := fmt.Sprintf("authors/%d/resources", author.ID)
 	req, err := r.Client.NewRequest("GET", url, nil)
 	if err != nil {
@@ -184,7 +205,7 @@ func (r *SpigotMC) GetAuthorResources(author *spiget.Author) ([]*SpigotResource,
 	}
 
 	var resources []spiget.Resource
-	rsp, err := r.Client.Do(r.Lock, req, &resources)
+	rsp, err := r.Client.Do(ctx, req, &resources)
 	if err != nil {
 		return nil, rsp, r.parseError(err)
 	}
@@ -216,9 +237,9 @@ func (r *SpigotResource) GetRepository() bucket.Repository {
 	return r.repository
 }
 
-func (r *SpigotResource) requireComplete() error {
+func (r *SpigotResource) requireComplete(ctx context.Context) error {
 	if !r.completed {
-		res, err := r.repository.Get(r.GetIdentifier())
+		res, err := r.repository.Get(ctx, r.GetIdentifier())
 		if err != nil {
 			return err
 		}
@@ -230,8 +251,8 @@ func (r *SpigotResource) requireComplete() error {
 	return nil
 }
 
-func (r *SpigotResource) GetLatestVersion() (bucket.RemoteVersion, error) {
-	vers, err := r.GetVersions(1)
+func (r *SpigotResource) GetLatestVersion(ctx context.Context) (bucket.RemoteVersion, error) {
+	vers, err := r.GetVersions(ctx, 1)
 	if err != nil {
 		return nil, r.repository.parseError(err)
 	}
@@ -239,34 +260,34 @@ func (r *SpigotResource) GetLatestVersion() (bucket.RemoteVersion, error) {
 	return vers[0], nil
 }
 
-func (r *SpigotResource) GetVersionByID(identifier string) (bucket.RemoteVersion, error) {
+func (r *SpigotResource) GetVersionByID(ctx context.Context, identifier string) (bucket.RemoteVersion, error) {
 	i, err := strconv.Atoi(identifier)
 	if err != nil {
 		return nil, r.repository.parseError(err)
 	}
 
-	for _, v := range r.GetVersionsInfo() {
+	for _, v := range r.GetVersionsInfo(ctx) {
 		if v.ID == i {
-			return v.Get()
+			return v.Get(ctx)
 		}
 	}
 
 	return nil, r.repository.parseError(fmt.Errorf("version %s not found", identifier))
 }
 
-func (r *SpigotResource) GetVersions(limit int) ([]bucket.RemoteVersion, error) {
-	err := r.requireComplete()
+func (r *SpigotResource) GetVersions(ctx context.Context, limit int) ([]bucket.RemoteVersion, error) {
+	err := r.requireComplete(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	vers := make([]bucket.RemoteVersion, 0, len(r.Versions))
-	for i, info := range r.GetVersionsInfo() {
+	for i, info := range r.GetVersionsInfo(ctx) {
 		if limit > 0 && i >= limit {
 			break
 		}
 
-		ver, err := info.Get()
+		ver, err := info.Get(ctx)
 		if err != nil {
 			return nil, r.repository.parseError(err)
 		}
@@ -277,8 +298,8 @@ func (r *SpigotResource) GetVersions(limit int) ([]bucket.RemoteVersion, error)
 	return vers, nil
 }
 
-func (r *SpigotResource) GetVersionsInfo() []SpigotVersionInfo {
-	err := r.requireComplete()
+func (r *SpigotResource) GetVersionsInfo(ctx context.Context) []SpigotVersionInfo {
+	err := r.requireComplete(ctx)
 	if err != nil {
 		return nil
 	}
@@ -291,8 +312,8 @@ func (r *SpigotResource) GetVersionsInfo() []SpigotVersionInfo {
 	return vers
 }
 
-func (r *SpigotResource) GetVersionIdentifiers() ([]string, error) {
-	err := r.requireComplete()
+func (r *SpigotResource) GetVersionIdentifiers(ctx context.Context) ([]string, error) {
+	err := r.requireComplete(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -305,16 +326,41 @@ func (r *SpigotResource) GetVersionIdentifiers() ([]string, error) {
 	return identifiers, nil
 }
 
-func (r *SpigotResource) GetLatestCompatible(platform bucket.PlatformType) (bucket.RemoteVersion, error) {
-	for _, info := range r.GetVersionsInfo() {
+func (r *SpigotResource) GetLatestCompatible(ctx context.Context, platform bucket.PlatformType) (bucket.RemoteVersion, error) {
+	return r.GetLatestCompatibleFor(ctx, platform, r.repository.gameVersion())
+}
+
+func (r *SpigotResource) GetLatestCompatibleFor(ctx context.Context, platform bucket.PlatformType, game string) (bucket.RemoteVersion, error) {
+	if err := r.requireComplete(ctx); err != nil {
+		return nil, err
+	}
+
+	// Spigot versions don't list game versions, only the resource does
+	if game != "" && len(r.TestedVersions) > 0 &&
+		!slices.ContainsFunc(r.TestedVersions, func(v string) bool {
+			return bucket.SameMinorVersion(v, game)
+		}) {
+		return nil, r.repository.parseError(fmt.Errorf("not tested on minecraft %s", game))
+	}
+
+	for _, info := range r.GetVersionsInfo(ctx) {
 		if info.Compatible(platform) {
-			return info.Get()
+			return info.Get(ctx)
 		}
 	}
 
 	return nil, r.repository.parseError(fmt.Errorf("no compatible version found"))
 }
 
+// gameVersion is the Minecraft version of the context, empty if unknown
+func (r *SpigotMC) gameVersion() string {
+	if r == nil || r.Context == nil || r.Context.Platform == nil {
+		return ""
+	}
+
+	return r.Context.Platform.GameVersion()
+}
+
 func (r *SpigotMC) categoryCompatible(scat spiget.Category, platform bucket.PlatformType) bool {
 	cat, err := spigotmc.GetCategory(scat)
 	if err != nil {
@@ -387,6 +433,14 @@ func (r *SpigotResource) GetWebsite() string {
 	return r.SourceCodeLink
 }
 
+func (r *SpigotResource) GetSourceURL() string {
+	return r.SourceCodeLink
+}
+
+func (r *SpigotResource) GetDownloads() int {
+	return r.Resource.Downloads
+}
+
 func (v *SpigotVersionInfo) GetVersion() string {
 	return strconv.Itoa(v.ID)
 }
@@ -395,11 +449,15 @@ func (v *SpigotVersionInfo) GetVersionName() string {
 	return v.UUID
 }
 
+func (v *SpigotVersionInfo) GetVersionIdentifier() string {
+	return strconv.Itoa(v.ID)
+}
+
 func (v *SpigotVersionInfo) GetCategory() (*spigotmc.Category, error) {
 	return spigotmc.GetCategory(v.Category)
 }
 
-func (v *SpigotVersionInfo) Get() (*SpigotVersion, error) {
+func (v *SpigotVersionInfo) Get(ctx context.Context) (*SpigotVersion, error) {
 	u := fmt.Sprintf("resources/%d/versions/%d", v.Resource.ID, v.ID)
 	req, err := v.repository.Client.NewRequest("GET", u, nil)
 	if err != nil {
@@ -407,7 +465,7 @@ func (v *SpigotVersionInfo) Get() (*SpigotVersion, error) {
 	}
 
 	ver := SpigotVersion{SpigotVersionInfo: *v}
-	_, err = v.repository.Client.Do(v.repository.Lock, req, &ver)
+	_, err = v.repository.Client.Do(ctx, req, &ver)
 
 	return &ver, v.repository.parseError(err)
 }
@@ -420,7 +478,7 @@ func (v *SpigotVersion) GetDependencies() []bucket.Dependency {
 	panic("not implemented") // TODO: Implement
 }
 
-func (v *SpigotVersion) GetFiles() ([]bucket.RemoteFile, error) {
+func (v *SpigotVersion) GetFiles(_ context.Context) ([]bucket.RemoteFile, error) {
 	return []bucket.RemoteFile{&SpigotFile{SpigotVersion: v}}, nil
 }
 
@@ -432,12 +490,12 @@ func (f *SpigotFile) Optional() bool {
 	return false
 }
 
-func (f *SpigotFile) Download() (io.ReadCloser, error) {
+func (f *SpigotFile) Download(ctx context.Context) (io.ReadCloser, error) {
 	if f.External {
 		return nil, f.repository.parseError(fmt.Errorf("external file not supported"))
 	}
 
-	r, err := f.repository.Client.Resources.DownloadVersion(f.repository.Lock, f.Resource.ID, f.ID)
+	r, err := f.repository.Client.Resources.DownloadVersion(ctx, f.Resource.ID, f.ID)
 	if err != nil {
 		return nil, f.repository.parseError(err)
 	}
@@ -453,5 +511,5 @@ func (r *SpigotMC) parseError(err error) error {
 	if err == nil {
 		return nil
 	}
-	return fmt.Errorf("spigotmc: %s", err)
+	return fmt.Errorf("spigotmc: %w", err)
 }
@@ -54,7 +54,9 @@ var Categories = []Category{
 
 	{ID: BungeeProxy, Subcategories: []Category{
 		{ID: Libraries9}, {ID: Transportation10}, {ID: Chat11}, {ID: Utilities12}, {ID: Misc13}, {ID: Universal},
-	}, compatiblePlatforms: []string{platforms.BungeeTypePlatform.Name}},
+	}, compatiblePlatforms: []string{platforms.BungeeTypePlatform.Name,
+		// SpigotMC has no category for velocity, its plugins are published here
+		platforms.VelocityTypePlatform.Name}},
 
 	{ID: Spigot, Subcategories: []Category{
 		{ID: Chat14}, {ID: Utilities15}, {ID: Misc16}, {ID: Fun}, {ID: WorldManagement},
@@ -12,17 +12,17 @@ type Repository interface {
 	Provider() string
 	PluginType() reflect.Type
 
-	Search(query string, max int) ([]RemotePlugin, int, error)
-	SearchAll(query string, max int) ([]RemotePlugin, int, error)
+	Search(ctx context.Context, query string, max int) ([]RemotePlugin, int, error)
+	SearchAll(ctx context.Context, query string, max int) ([]RemotePlugin, int, error)
 
-	Get(identifier string) (RemotePlugin, error)
-	Resolve(plugin Plugin) (RemotePlugin, []RemotePlugin, error)
+	Get(ctx context.Context, identifier string) (RemotePlugin, error)
+	Resolve(ctx context.Context, plugin Plugin) (RemotePlugin, []RemotePlugin, error)
 
 	// SupportsDependencies() bool // Can just check if version.(Depender)
 }
 
 type HashRepository interface {
-	GetByHash(hash string) (Plugin, error)
+	GetByHash(ctx context.Context, hash string) (Plugin, error)
 }
 
 type RemotePlugin interface {
@@ -32,11 +32,13 @@ type RemotePlugin interface {
 
 	GetRepository() Repository
 
-	GetLatestCompatible(PlatformType) (RemoteVersion, error)
-	GetLatestVersion() (RemoteVersion, error)
-	GetVersions(limit int) ([]RemoteVersion, error)
-	GetVersionByID(identifier string) (RemoteVersion, error)
-	GetVersionIdentifiers() ([]string, error)
+	GetLatestCompatible(ctx context.Context, platform PlatformType) (RemoteVersion, error)
+	// Like GetLatestCompatible, for a Minecraft version other than the server one
+	GetLatestCompatibleFor(ctx context.Context, platform PlatformType, game string) (RemoteVersion, error)
+	GetLatestVersion(ctx context.Context) (RemoteVersion, error)
+	GetVersions(ctx context.Context, limit int) ([]RemoteVersion, error)
+	GetVersionByID(ctx context.Context, identifier string) (RemoteVersion, error)
+	GetVersionIdentifiers(ctx context.Context) ([]string, error)
 }
 
 type RemoteVersion interface {
@@ -44,16 +46,21 @@ type RemoteVersion interface {
 	PlatformCompatible
 	NamedVersionable
 
-	GetFiles() ([]RemoteFile, error)
+	// The identifier accepted by GetVersionByID
+	GetVersionIdentifier() string
+	GetFiles(ctx context.Context) ([]RemoteFile, error)
 }
 
 type RemoteFile interface {
 	Name() string
 	Optional() bool
-	Download() (io.ReadCloser, error)
+	Download(ctx context.Context) (io.ReadCloser, error)
 	Verify() error
 }
 
+// LockRepository holds the context of the workspace that opened the
+// repository, used for requests made outside of an explicit call
+// (e.g. lazily loaded metadata)
 type LockRepository struct {
 	Repository
 
@@ -70,25 +77,48 @@ type HTTPRepository struct {
 type NamedRepository struct {
 	Repository
 	RepositoryConfig
+
+	limiter chan struct{}
 }
 
 func NewHTTPRepository(endpoint string) *HTTPRepository {
+	client := resty.New().SetTransport(SharedTransport())
+
 	return &HTTPRepository{
 		Endpoint:   endpoint,
-		HTTPClient: resty.New().SetHeader("User-Agent", UserAgent).SetBaseURL(endpoint),
+		HTTPClient: client.SetHeader("User-Agent", UserAgent).SetBaseURL(endpoint),
+	}
+}
+
+// Acquire blocks until the repository concurrency limit allows another request
+func (r NamedRepository) Acquire(ctx context.Context) error {
+	if r.limiter != nil {
+		select {
+		case r.limiter <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func (r NamedRepository) Release() {
+	if r.limiter != nil {
+		<-r.limiter
 	}
 }
 
 var Repositories = make(map[string]RepositoryConstructor)
 
-type RepositoryConstructor func(context.Context, *OpenContext, map[string]string) Repository
+type RepositoryConstructor func(context.Context, *OpenContext, map[string]string) (Repository, error)
 
 func RegisterRepository(name string, constr RepositoryConstructor) {
 	Repositories[name] = constr
 }
 
-func GetVersionNames(p RemotePlugin) ([]string, error) {
-	vers, err := p.GetVersions(0)
+func GetVersionNames(ctx context.Context, p RemotePlugin) ([]string, error) {
+	vers, err := p.GetVersions(ctx, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,259 @@
+package bucket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+const DefaultWorkers = 8
+const DefaultRepositoryConcurrency = 4
+
+type ResolutionHandler func(plugin Plugin, remote RemotePlugin) error
+
+// ResolvePlugins resolves every plugin on a bounded pool of workers, the
+// handler is called from the worker for every successful resolution.
+// No more plugins are dispatched once the context is cancelled
+func (c *OpenContext) ResolvePlugins(ctx context.Context, plugins []Plugin, handler ResolutionHandler) error {
+	var errs error
+	var lock sync.Mutex
+
+	collect := func(err error) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		errs = multierror.Append(errs, err)
+	}
+
+	jobs := make(chan Plugin)
+
+	var wait sync.WaitGroup
+	for i := 0; i < min(c.Config().ResolutionWorkers(), max(len(plugins), 1)); i++ {
+		wait.Add(1)
+
+		go func() {
+			defer wait.Done()
+
+			for pl := range jobs {
+				res, err := c.ResolvePlugin(ctx, pl)
+				if errors.Is(err, ErrUnresolvedCached) {
+					continue // Already reported when the resolution failed
+				}
+
+				if err != nil {
+					collect(fmt.Errorf("error resolving plugin %s: %w", pl.GetName(), err))
+					continue
+				}
+
+				if handler != nil {
+					if err := handler(pl, res); err != nil {
+						collect(err)
+					}
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for _, pl := range plugins {
+		select {
+		case jobs <- pl:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+
+	close(jobs)
+	wait.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return multierror.Append(errs, err)
+	}
+
+	return errs
+}
+
+func (c *OpenContext) ResolvePlugin(ctx context.Context, plugin Plugin) (RemotePlugin, error) {
+	var gerr error
+
+	if rem, ok := c.Plugins().GetAny(plugin.GetIdentifier()); ok {
+		return &rem, nil
+	}
+
+	local, isLocal := plugin.(*LocalPlugin)
+
+	var hash string
+	if isLocal {
+		var err error
+		if hash, err = local.Hash(); err != nil {
+			return nil, err
+		}
+
+		if rec, ok := c.GetUnresolved(local.GetIdentifier()); ok && !RetryUnresolved &&
+			rec.Valid(hash, c.Config().UnresolvedExpiration()) {
+			return nil, ErrUnresolvedCached
+		}
+	}
+
+	cmp := c.Comparator()
+
+	// Network failures don't prove that the plugin can't be resolved
+	transient := false
+
+	var best *resolution
+	for _, r := range c.resolutionOrder(plugin) {
+		// Lower priority repositories can't replace a match anymore
+		if best != nil && (best.preferred || r.Priority < best.repo.Priority) {
+			break
+		}
+
+		match, score, err := c.resolveIn(ctx, r, plugin, cmp)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			gerr = multierror.Append(gerr, err)
+			transient = transient || !errors.Is(err, ErrNoMatch)
+			continue
+		}
+
+		if best == nil || score > best.score {
+			best = &resolution{repo: r, match: match, score: score,
+				preferred: c.preferredRepository(plugin) == r.GetName()}
+		}
+	}
+
+	if best != nil {
+		r, match, score := best.repo, best.match, best.score
+
+		if isLocal {
+			res := CachedMatch(local, match, r, score)
+			res.database = c.PluginDatabase
+
+			if err := c.SavePlugin(res); err != nil {
+				return nil, err
+			}
+
+			if err := c.DeleteUnresolved(local.GetIdentifier()); err != nil {
+				return nil, err
+			}
+
+			return &res, nil
+		}
+
+		return match, nil
+	}
+
+	if gerr == nil {
+		gerr = fmt.Errorf("%w for \"%s\"", ErrNoMatch, plugin.GetName())
+	}
+
+	if isLocal && !transient {
+		if err := c.SaveUnresolved(Unresolved(local, hash)); err != nil {
+			return nil, multierror.Append(gerr, err)
+		}
+	}
+
+	return nil, gerr
+}
+
+type resolution struct {
+	repo      NamedRepository
+	match     RemotePlugin
+	score     float64
+	preferred bool
+}
+
+func (c *OpenContext) preferredRepository(plugin Plugin) string {
+	if pc, ok := c.Config().PluginSettings(plugin); ok {
+		return pc.Repository
+	}
+
+	return ""
+}
+
+// resolutionOrder puts the preferred repository of the plugin, if any,
+// before the others, which are sorted by priority
+func (c *OpenContext) resolutionOrder(plugin Plugin) []NamedRepository {
+	repos := c.SortedRepositories()
+
+	pref := c.preferredRepository(plugin)
+	if pref == "" {
+		return repos
+	}
+
+	i := slices.IndexFunc(repos, func(r NamedRepository) bool {
+		return r.GetName() == pref
+	})
+
+	if i < 0 {
+		log.Printf("warn: preferred repository \"%s\" of %s is not configured\n", pref, plugin.GetName())
+		return repos
+	}
+
+	first := repos[i]
+	return append([]NamedRepository{first}, slices.Delete(repos, i, i+1)...)
+}
+
+// resolveIn finds the best candidate for the plugin in a single repository,
+// respecting its concurrency limit
+func (c *OpenContext) resolveIn(ctx context.Context, r NamedRepository, plugin Plugin, cmp *Comparator) (RemotePlugin, float64, error) {
+	if err := r.Acquire(ctx); err != nil {
+		return nil, 0, err
+	}
+
+	defer r.Release()
+
+	_, candidates, err := r.Resolve(ctx, plugin)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(candidates) == 0 {
+		return nil, 0, fmt.Errorf("%w for \"%s\"", ErrNoMatch, plugin.GetName())
+	}
+
+	keys := make([]float64, 0, len(candidates))
+	scores := make(map[float64]RemotePlugin)
+	for _, pl := range candidates {
+		if pl.Compatible(c.Platform.Type()) && c.Platform.Type().Supports(pl) {
+			score := cmp.Index(ctx, plugin, pl)
+			keys = append(keys, score)
+			scores[score] = pl
+
+			if score >= 1.0 {
+				break
+			}
+		}
+	}
+
+	if DEBUG {
+		for _, v := range keys {
+			log.Printf("%s candidate: %s\t\t\tscore: %f [%s]\n",
+				plugin.GetName(), scores[v].GetName(), v, scores[v].GetRepository().Provider())
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, 0, fmt.Errorf(
+			"%w: %d candidates found for \"%s\" but none are compatible with platform \"%s\"",
+			ErrNoMatch, len(candidates), plugin.GetName(), c.Platform.Type().Name)
+	}
+
+	slices.Sort(keys)
+	match := keys[len(keys)-1]
+
+	if match < cmp.Threshold {
+		return nil, 0, fmt.Errorf(
+			"%w: %d candidates found for \"%s\" but none satisfy similarity treshold, closest match was %f",
+			ErrNoMatch, len(candidates), plugin.GetName(), match)
+	}
+
+	return scores[match], match, nil
+}
@@ -0,0 +1,476 @@
+package bucket
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+const (
+	SecretsFolder      = "secrets"
+	DefaultSecretStore = "default"
+	SecretStoreExt     = ".vault"
+
+	// Passphrase of the file stores, asked on the terminal if unset
+	SecretsPassphraseEnv = "BUCKET_SECRETS_PASSPHRASE"
+	DefaultSecretsPrefix = "BUCKET_SECRET_"
+
+	SecretMask = "******"
+
+	// Shorter values are too common to be masked in the output
+	minMaskedLength = 3
+)
+
+var (
+	ErrSecretNotFound = errors.New("secret not found")
+	ErrBadPassphrase  = errors.New("wrong passphrase or corrupt secret store")
+)
+
+var secretName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Providers used when no secrets are configured
+var DefaultSecretProviders = []SecretConfig{{Provider: "env"}, {Provider: "file"}}
+
+// SecretProvider returns the values of the ${secret:NAME} references
+// in the rendered configs, or ErrSecretNotFound
+type SecretProvider interface {
+	Secret(ctx context.Context, name string) (string, error)
+}
+
+type SecretProviderConstructor func(*OpenContext, map[string]string) (SecretProvider, error)
+
+var SecretProviders = make(map[string]SecretProviderConstructor)
+
+func RegisterSecretProvider(name string, constr SecretProviderConstructor) {
+	SecretProviders[name] = constr
+}
+
+func init() {
+	RegisterSecretProvider("env", NewEnvSecrets)
+	RegisterSecretProvider("file", func(_ *OpenContext, opts map[string]string) (SecretProvider, error) {
+		return OpenSecretStore(opts["store"])
+	})
+	RegisterSecretProvider("exec", NewExecSecrets)
+}
+
+// Resolved secret values, masked in everything bucket prints
+var secretMasks struct {
+	sync.RWMutex
+	values []string
+}
+
+// MaskSecret hides the value from the output from now on
+func MaskSecret(value string) {
+	if len(value) < minMaskedLength {
+		return
+	}
+
+	secretMasks.Lock()
+	defer secretMasks.Unlock()
+
+	if !slices.Contains(secretMasks.values, value) {
+		secretMasks.values = append(secretMasks.values, value)
+
+		// Longer values first, in case one contains another
+		slices.SortFunc(secretMasks.values, func(a, b string) int { return len(b) - len(a) })
+	}
+}
+
+func MaskSecrets(s string) string {
+	secretMasks.RLock()
+	defer secretMasks.RUnlock()
+
+	for _, v := range secretMasks.values {
+		s = strings.ReplaceAll(s, v, SecretMask)
+	}
+
+	return s
+}
+
+type maskingWriter struct {
+	io.Writer
+}
+
+// NewMaskingWriter masks the secret values in every write, loggers
+// write a line at a time so values are never split between writes
+func NewMaskingWriter(w io.Writer) io.Writer {
+	return maskingWriter{w}
+}
+
+func (w maskingWriter) Write(b []byte) (int, error) {
+	if _, err := io.WriteString(w.Writer, MaskSecrets(string(b))); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+// SecretProviders opens the configured providers once, in order
+func (c *OpenContext) SecretProviders() ([]SecretProvider, error) {
+	c.secretsOnce.Do(func() {
+		configs := c.Config().Secrets
+		if len(configs) == 0 {
+			configs = DefaultSecretProviders
+		}
+
+		for _, sc := range configs {
+			constr, ok := SecretProviders[sc.Provider]
+			if !ok {
+				c.secretsErr = fmt.Errorf("unknown secret provider: %s", sc.Provider)
+				return
+			}
+
+			provider, err := constr(c, sc.Options)
+			if err != nil {
+				c.secretsErr = fmt.Errorf("secret provider %s: %w", sc.Provider, err)
+				return
+			}
+
+			c.secrets = append(c.secrets, provider)
+		}
+	})
+
+	return c.secrets, c.secretsErr
+}
+
+// Secret asks the providers for the secret in order and masks its value
+func (c *OpenContext) Secret(ctx context.Context, name string) (string, error) {
+	if !secretName.MatchString(name) {
+		return "", fmt.Errorf("invalid secret name: %q", name)
+	}
+
+	providers, err := c.SecretProviders()
+	if err != nil {
+		return "", err
+	}
+
+	for _, p := range providers {
+		value, err := p.Secret(ctx, name)
+		if errors.Is(err, ErrSecretNotFound) {
+			continue
+		} else if err != nil {
+			return "", fmt.Errorf("secret %s: %w", name, err)
+		}
+
+		MaskSecret(value)
+		return value, nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+}
+
+// EnvSecrets reads the secrets from the environment, as the prefix
+// followed by the name in upper case with dashes and dots as underscores
+type EnvSecrets struct {
+	Prefix string
+}
+
+func NewEnvSecrets(_ *OpenContext, opts map[string]string) (SecretProvider, error) {
+	prefix, ok := opts["prefix"]
+	if !ok {
+		prefix = DefaultSecretsPrefix
+	}
+
+	return &EnvSecrets{Prefix: prefix}, nil
+}
+
+func (e *EnvSecrets) Secret(_ context.Context, name string) (string, error) {
+	key := e.Prefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+	if value, ok := os.LookupEnv(key); ok {
+		return value, nil
+	}
+
+	return "", ErrSecretNotFound
+}
+
+// ExecSecrets runs a command printing the secret, like a password manager.
+// The name replaces {name} in the arguments or is passed as the last one
+type ExecSecrets struct {
+	Command []string
+}
+
+func NewExecSecrets(_ *OpenContext, opts map[string]string) (SecretProvider, error) {
+	command := strings.Fields(opts["command"])
+	if len(command) == 0 {
+		return nil, errors.New("missing command option")
+	}
+
+	return &ExecSecrets{Command: command}, nil
+}
+
+func (e *ExecSecrets) Secret(ctx context.Context, name string) (string, error) {
+	args := slices.Clone(e.Command)
+	replaced := false
+	for i, a := range args {
+		if strings.Contains(a, "{name}") {
+			args[i] = strings.ReplaceAll(a, "{name}", name)
+			replaced = true
+		}
+	}
+
+	if !replaced {
+		args = append(args, name)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// SecretStore keeps secrets in a file encrypted with AES-256-GCM,
+// using a key derived from a passphrase through scrypt
+type SecretStore struct {
+	Path string
+
+	once    sync.Once
+	err     error
+	key     []byte
+	salt    []byte
+	secrets map[string]string
+}
+
+// SecretStorePath is the file of a named store under ~/.bucket/secrets
+func SecretStorePath(store string) (string, error) {
+	if store == "" {
+		store = DefaultSecretStore
+	}
+
+	if !secretName.MatchString(store) {
+		return "", fmt.Errorf("invalid secret store name: %q", store)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".bucket", SecretsFolder, store+SecretStoreExt), nil
+}
+
+// Stores are shared by the contexts, to ask for the passphrase once
+var openStores struct {
+	sync.Mutex
+	stores map[string]*SecretStore
+}
+
+// OpenSecretStore opens a named store, it's only decrypted when first used
+func OpenSecretStore(store string) (*SecretStore, error) {
+	path, err := SecretStorePath(store)
+	if err != nil {
+		return nil, err
+	}
+
+	openStores.Lock()
+	defer openStores.Unlock()
+
+	if s, ok := openStores.stores[path]; ok {
+		return s, nil
+	}
+
+	if openStores.stores == nil {
+		openStores.stores = make(map[string]*SecretStore)
+	}
+
+	s := &SecretStore{Path: path}
+	openStores.stores[path] = s
+
+	return s, nil
+}
+
+func (s *SecretStore) Secret(_ context.Context, name string) (string, error) {
+	if err := s.load(); err != nil {
+		return "", err
+	}
+
+	if value, ok := s.secrets[name]; ok {
+		return value, nil
+	}
+
+	return "", ErrSecretNotFound
+}
+
+func (s *SecretStore) Names() ([]string, error) {
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(s.secrets))
+	for name := range s.secrets {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+	return names, nil
+}
+
+// Set changes a secret and saves the store, an empty value deletes it
+func (s *SecretStore) Set(name, value string) error {
+	if !secretName.MatchString(name) {
+		return fmt.Errorf("invalid secret name: %q", name)
+	}
+
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	if value == "" {
+		delete(s.secrets, name)
+	} else {
+		s.secrets[name] = value
+	}
+
+	return s.save()
+}
+
+func (s *SecretStore) load() error {
+	s.once.Do(func() {
+		s.secrets = make(map[string]string)
+
+		// New stores get their key when first saved
+		data, err := os.ReadFile(s.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
+			s.err = err
+			return
+		}
+
+		if len(data) < 16 {
+			s.err = ErrBadPassphrase
+			return
+		}
+
+		s.salt = data[:16]
+		if s.key, s.err = storeKey(s.salt); s.err != nil {
+			return
+		}
+
+		plain, err := openSealed(s.key, data[16:])
+		if err != nil {
+			s.err = err
+			return
+		}
+
+		s.err = json.Unmarshal(plain, &s.secrets)
+	})
+
+	return s.err
+}
+
+func (s *SecretStore) save() error {
+	if s.key == nil {
+		s.salt = make([]byte, 16)
+		if _, err := rand.Read(s.salt); err != nil {
+			return err
+		}
+
+		key, err := storeKey(s.salt)
+		if err != nil {
+			return err
+		}
+
+		s.key = key
+	}
+
+	plain, err := json.Marshal(s.secrets)
+	if err != nil {
+		return err
+	}
+
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data := append(slices.Clone(s.salt), nonce...)
+	data = gcm.Seal(data, nonce, plain, nil)
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+		return err
+	}
+
+	part := s.Path + StagingSuffix
+	if err := os.WriteFile(part, data, 0600); err != nil {
+		os.Remove(part)
+		return err
+	}
+
+	return os.Rename(part, s.Path)
+}
+
+func openSealed(key, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrBadPassphrase
+	}
+
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+
+	return plain, nil
+}
+
+func storeKey(salt []byte) ([]byte, error) {
+	passphrase, ok := os.LookupEnv(SecretsPassphraseEnv)
+	if !ok {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return nil, fmt.Errorf("no passphrase for the secret store, set %s", SecretsPassphraseEnv)
+		}
+
+		fmt.Fprint(os.Stderr, "secret store passphrase: ")
+		read, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+
+		if err != nil {
+			return nil, err
+		}
+
+		passphrase = string(read)
+	}
+
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+}
@@ -0,0 +1,87 @@
+package bucket
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MRtecno98/afero"
+)
+
+func TestSecretStore(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(SecretsPassphraseEnv, "correct horse")
+
+	store, err := OpenSecretStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Secret(context.Background(), "db-password"); !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("expected a missing secret in a new store, got %v", err)
+	}
+
+	if err := store.Set("db-password", "hunter22"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(store.Path)
+	if strings.Contains(string(data), "hunter22") {
+		t.Fatal("secret stored in clear")
+	}
+
+	reopened := &SecretStore{Path: store.Path}
+	if value, err := reopened.Secret(context.Background(), "db-password"); err != nil || value != "hunter22" {
+		t.Fatalf("wrong secret read back: %q %v", value, err)
+	}
+
+	t.Setenv(SecretsPassphraseEnv, "wrong")
+	wrong := &SecretStore{Path: store.Path}
+	if _, err := wrong.Secret(context.Background(), "db-password"); !errors.Is(err, ErrBadPassphrase) {
+		t.Fatalf("expected a bad passphrase error, got %v", err)
+	}
+}
+
+func TestOverlaySecrets(t *testing.T) {
+	base := t.TempDir()
+	os.WriteFile(filepath.Join(base, "config.yml"), []byte(
+		"password: ${secret:db-password}\napi-key: ${secret:api-key}\n"), 0644)
+
+	t.Setenv("BUCKET_SECRET_DB_PASSWORD", "s3cr3t-pass")
+
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	c := &OpenContext{Fs: fs, Lock: context.Background(), LocalConfig: &Config{
+		Overlays: []OverlayConfig{{Base: base, Target: "plugins/LuckPerms"}},
+		Secrets: []SecretConfig{
+			{Provider: "env"},
+			{Provider: "exec", Options: map[string]string{"command": "echo key-{name}"}},
+		},
+	}}
+
+	if _, err := c.ApplyOverlays(false); err != nil {
+		t.Fatal(err)
+	}
+
+	config, _ := fs.ReadFile("plugins/LuckPerms/config.yml")
+	if string(config) != "password: s3cr3t-pass\napi-key: key-api-key\n" {
+		t.Fatalf("secrets not rendered:\n%s", config)
+	}
+
+	diff := UnifiedDiff("old", "new", []byte("password: old\n"), config)
+	if strings.Contains(diff, "s3cr3t-pass") || !strings.Contains(diff, SecretMask) {
+		t.Fatalf("secret not masked in the diff:\n%s", diff)
+	}
+
+	// Missing secrets fail the rendering instead of being left in
+	c = &OpenContext{Fs: fs, Lock: context.Background(), LocalConfig: &Config{
+		Overlays: c.LocalConfig.Overlays,
+		Secrets:  []SecretConfig{{Provider: "env"}},
+	}}
+
+	if _, _, err := c.RenderOverlays(); !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("expected a missing secret error, got %v", err)
+	}
+}
@@ -0,0 +1,439 @@
+package bucket
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"gopkg.in/yaml.v2"
+)
+
+// ServerSchema lists the known keys of a server config file, as dotted
+// paths. A key ending in ".*" allows anything under it
+type ServerSchema []string
+
+func (s ServerSchema) Allows(key string) bool {
+	for _, k := range s {
+		if k == key || strings.HasSuffix(k, ".*") && strings.HasPrefix(key, k[:len(k)-1]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ServerSetting is a value of a server config file at a key path
+type ServerSetting struct {
+	File  string
+	Path  []string
+	Value interface{}
+}
+
+func (s ServerSetting) Key() string {
+	return strings.Join(s.Path, ".")
+}
+
+// ServerChange is a setting that differs from the server config file
+type ServerChange struct {
+	ServerSetting
+
+	Old     string
+	Missing bool
+	New     string
+}
+
+// ServerSettings flattens the server section of the config, both dotted
+// keys and nested mappings are split in paths, sorted by file and key
+func (c *OpenContext) ServerSettings() []ServerSetting {
+	var settings []ServerSetting
+	for file, values := range c.Config().Server {
+		settings = append(settings, flattenSettings(file, nil, values)...)
+	}
+
+	slices.SortStableFunc(settings, func(a, b ServerSetting) int {
+		if a.File != b.File {
+			return strings.Compare(a.File, b.File)
+		}
+
+		return strings.Compare(a.Key(), b.Key())
+	})
+
+	return settings
+}
+
+func flattenSettings(file string, parent []string, values yaml.MapSlice) []ServerSetting {
+	var settings []ServerSetting
+	for _, item := range values {
+		p := append(slices.Clone(parent), strings.Split(fmt.Sprint(item.Key), ".")...)
+		if nested, ok := item.Value.(yaml.MapSlice); ok {
+			settings = append(settings, flattenSettings(file, p, nested)...)
+		} else {
+			settings = append(settings, ServerSetting{File: file, Path: p, Value: item.Value})
+		}
+	}
+
+	return settings
+}
+
+// ValidateServerSettings checks the files and keys against the schemas of the platform
+func (c *OpenContext) ValidateServerSettings(settings []ServerSetting) error {
+	if c.Platform == nil {
+		return errors.New("no platform detected")
+	}
+
+	schemas := c.Platform.Type().ServerSchemas()
+
+	var errs *multierror.Error
+	for _, s := range settings {
+		schema, ok := schemas[s.File]
+		if !ok {
+			errs = multierror.Append(errs, fmt.Errorf("%s isn't a %s config", s.File, c.PlatformName()))
+		} else if !schema.Allows(s.Key()) {
+			errs = multierror.Append(errs, fmt.Errorf("unknown key in %s: %s", s.File, s.Key()))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// ConfigureServer applies the server section to the server config files,
+// editing the lines of the changed keys only so comments and order stay.
+// With check set the files aren't written, only the changes are returned
+func (c *OpenContext) ConfigureServer(check bool) ([]ServerChange, error) {
+	settings := c.ServerSettings()
+	if err := c.ValidateServerSettings(settings); err != nil {
+		return nil, err
+	}
+
+	vars := c.OverlayVariables()
+	unknown := make(map[string]bool)
+
+	var changes []ServerChange
+	for _, file := range slices.Compact(fileNames(settings)) {
+		data, err := c.Fs.ReadFile(file)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		editor := newServerEditor(file, SplitLines(string(data)))
+
+		changed := false
+		for _, s := range settings {
+			if s.File != file {
+				continue
+			}
+
+			// Strings can reference variables and secrets, like overlays
+			rendered, err := c.substituteYAML(s.Value, vars, unknown)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", file, s.Key(), err)
+			}
+
+			s.Value = rendered
+
+			change, err := editor.set(s, check)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+
+			if change != nil {
+				changes = append(changes, *change)
+				changed = true
+			}
+		}
+
+		if check || !changed {
+			continue
+		}
+
+		if err := c.Fs.MkdirAll(path.Dir(file), 0755); err != nil {
+			return nil, err
+		}
+
+		if err := c.Fs.WriteFile(file, []byte(strings.Join(editor.lines, "\n")+"\n"), 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	return changes, nil
+}
+
+func fileNames(settings []ServerSetting) []string {
+	names := make([]string, len(settings))
+	for i, s := range settings {
+		names[i] = s.File
+	}
+
+	return names
+}
+
+// serverEditor changes values in the lines of a properties or YAML file
+type serverEditor struct {
+	lines      []string
+	properties bool
+}
+
+func newServerEditor(file string, lines []string) *serverEditor {
+	return &serverEditor{lines: lines, properties: path.Ext(file) == ".properties"}
+}
+
+func (e *serverEditor) set(s ServerSetting, check bool) (*ServerChange, error) {
+	if e.properties {
+		return e.setProperty(s, check)
+	}
+
+	return e.setYAML(s, check)
+}
+
+func (e *serverEditor) setProperty(s ServerSetting, check bool) (*ServerChange, error) {
+	if _, ok := s.Value.(yaml.MapSlice); ok || reflect.ValueOf(s.Value).Kind() == reflect.Slice {
+		return nil, fmt.Errorf("%s: properties can only be scalars", s.Key())
+	}
+
+	key, value := s.Key(), ""
+	if s.Value != nil {
+		value = fmt.Sprint(s.Value)
+	}
+
+	line := key + "=" + value
+	for i, l := range e.lines {
+		trimmed := strings.TrimSpace(l)
+		if trimmed == "" || trimmed[0] == '#' || trimmed[0] == '!' {
+			continue
+		}
+
+		k, v, _ := strings.Cut(trimmed, "=")
+		if strings.TrimSpace(k) != key {
+			continue
+		}
+
+		if v = strings.TrimSpace(v); v == value {
+			return nil, nil
+		}
+
+		if !check {
+			e.lines[i] = line
+		}
+
+		return &ServerChange{ServerSetting: s, Old: v, New: value}, nil
+	}
+
+	if !check {
+		e.lines = append(e.lines, line)
+	}
+
+	return &ServerChange{ServerSetting: s, Missing: true, New: value}, nil
+}
+
+// yamlEntry is a mapping key line of a YAML file with its full path
+type yamlEntry struct {
+	line   int
+	indent int
+	path   []string
+
+	// Raw text up to the colon, and the value and comment after it
+	key     string
+	value   string
+	comment string
+}
+
+func (e *serverEditor) yamlEntries() []yamlEntry {
+	var entries, stack []yamlEntry
+	for i, l := range e.lines {
+		entry, ok := parseYAMLLine(l)
+		if !ok {
+			continue
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= entry.indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		entry.line = i
+		for _, s := range stack {
+			entry.path = append(entry.path, s.path[len(s.path)-1])
+		}
+
+		entry.path = append(entry.path, unquoteKey(strings.TrimSpace(entry.key)))
+		entries = append(entries, entry)
+		stack = append(stack, entry)
+	}
+
+	return entries
+}
+
+func parseYAMLLine(line string) (yamlEntry, bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	if trimmed == "" || trimmed[0] == '#' || trimmed[0] == '-' {
+		return yamlEntry{}, false
+	}
+
+	// The key ends at the first colon followed by a space or at the end
+	// of the line, after the closing quote for quoted keys
+	start := 0
+	if q := trimmed[0]; q == '"' || q == '\'' {
+		if j := strings.IndexByte(trimmed[1:], q); j >= 0 {
+			start = j + 2
+		}
+	}
+
+	end := -1
+	for i := start; i < len(trimmed); i++ {
+		if trimmed[i] == ':' && (i == len(trimmed)-1 || trimmed[i+1] == ' ') {
+			end = i
+			break
+		}
+	}
+
+	if end < 0 {
+		return yamlEntry{}, false
+	}
+
+	indent := len(line) - len(trimmed)
+	value, comment := splitYAMLComment(strings.TrimLeft(trimmed[end+1:], " "))
+
+	return yamlEntry{indent: indent, key: line[:indent+end], value: value, comment: comment}, true
+}
+
+// splitYAMLComment separates the trailing comment of a value, outside of quotes
+func splitYAMLComment(s string) (string, string) {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' '):
+			return strings.TrimRight(s[:i], " "), " " + s[i:]
+		}
+	}
+
+	return s, ""
+}
+
+func unquoteKey(key string) string {
+	if len(key) >= 2 && (key[0] == '"' || key[0] == '\'') && key[len(key)-1] == key[0] {
+		return key[1 : len(key)-1]
+	}
+
+	return key
+}
+
+// yamlValue formats a scalar or a list inline, in flow style
+func yamlValue(v interface{}) (string, error) {
+	if list, ok := v.([]interface{}); ok {
+		items := make([]string, len(list))
+		for i, item := range list {
+			var err error
+			if items[i], err = yamlValue(item); err != nil {
+				return "", err
+			}
+		}
+
+		return "[" + strings.Join(items, ", ") + "]", nil
+	}
+
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+func (e *serverEditor) setYAML(s ServerSetting, check bool) (*ServerChange, error) {
+	value, err := yamlValue(s.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.Key(), err)
+	}
+
+	entries := e.yamlEntries()
+
+	// Deepest existing section on the path
+	var parent *yamlEntry
+	for i := range entries {
+		entry := &entries[i]
+		if slices.Equal(entry.path, s.Path) {
+			var current interface{}
+			if err := yaml.Unmarshal([]byte(entry.value), &current); err == nil &&
+				reflect.DeepEqual(current, s.Value) {
+				return nil, nil
+			}
+
+			if !check {
+				e.lines[entry.line] = entry.key + ": " + value + entry.comment
+			}
+
+			return &ServerChange{ServerSetting: s, Old: entry.value, New: value}, nil
+		}
+
+		if len(entry.path) < len(s.Path) && slices.Equal(entry.path, s.Path[:len(entry.path)]) &&
+			(parent == nil || len(entry.path) > len(parent.path)) {
+			parent = entry
+		}
+	}
+
+	if parent != nil && parent.value != "" {
+		return nil, fmt.Errorf("%s: %s isn't a section", s.Key(), strings.Join(parent.path, "."))
+	}
+
+	if !check {
+		e.insertYAML(entries, parent, s.Path, value)
+	}
+
+	return &ServerChange{ServerSetting: s, Missing: true, New: value}, nil
+}
+
+// insertYAML adds the missing sections and the key at the end of the parent
+func (e *serverEditor) insertYAML(entries []yamlEntry, parent *yamlEntry, keys []string, value string) {
+	at, indent, step := len(e.lines), 0, 2
+	if parent != nil {
+		keys = keys[len(parent.path):]
+		at, indent = parent.line+1, parent.indent+step
+
+		for _, entry := range entries {
+			if entry.line <= parent.line {
+				continue
+			} else if entry.indent <= parent.indent {
+				break
+			}
+
+			if at == parent.line+1 {
+				indent, step = entry.indent, entry.indent-parent.indent
+			}
+
+			at = entry.line + 1
+		}
+
+		// Past the lines of the last child, like lists and block scalars
+		for at < len(e.lines) {
+			trimmed := strings.TrimLeft(e.lines[at], " ")
+			if trimmed == "" || len(e.lines[at])-len(trimmed) <= parent.indent {
+				break
+			}
+
+			at++
+		}
+	}
+
+	var lines []string
+	for i, key := range keys {
+		k, _ := yamlValue(key)
+		line := strings.Repeat(" ", indent+i*step) + k + ":"
+		if i == len(keys)-1 {
+			line += " " + value
+		}
+
+		lines = append(lines, line)
+	}
+
+	e.lines = slices.Insert(e.lines, at, lines...)
+}
@@ -0,0 +1,98 @@
+package bucket
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/MRtecno98/afero"
+	"gopkg.in/yaml.v2"
+)
+
+const testSpigotYml = `# Spigot config
+settings:
+  debug: false # verbose logs
+  bungeecord: false
+
+  timeout-time: 60
+  attribute:
+    maxHealth:
+      max: 2048.0
+messages:
+  whitelist: You are not whitelisted!
+world-settings:
+  default:
+    verbose: false
+`
+
+func TestConfigureServer(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	fs.WriteFile("server.properties", []byte("#Minecraft server properties\nmax-players=20\nmotd=A Minecraft Server\n"), 0644)
+	fs.WriteFile("spigot.yml", []byte(testSpigotYml), 0644)
+
+	var server map[string]yaml.MapSlice
+	yaml.Unmarshal([]byte(`
+server.properties:
+  max-players: 100
+  motd: Welcome to ${CONTEXT}
+  rcon.port: 25575
+spigot.yml:
+  settings.bungeecord: ${BUNGEE}
+  settings:
+    debug: false
+    restart-on-crash: false
+  world-settings.default.entity-tracking-range.players: 64
+`), &server)
+
+	plt := PlatformType{Name: "test", ServerConfigs: map[string]ServerSchema{
+		"server.properties": {"max-players", "motd", "rcon.port"},
+		"spigot.yml":        {"settings.bungeecord", "settings.debug", "settings.restart-on-crash", "world-settings.*"},
+	}}
+
+	// Single variables keep their type, Bukkit ignores quoted booleans
+	c := &OpenContext{Context: Context{Name: "lobby", Variables: map[string]string{"BUNGEE": "true"}}, Fs: fs, Lock: context.Background(),
+		Platform: &stubPlatform{plt}, LocalConfig: &Config{Server: server}}
+
+	changes, err := c.ConfigureServer(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changes) != 6 {
+		t.Fatalf("expected 6 drifted settings, got %+v", changes)
+	}
+
+	if data, _ := fs.ReadFile("spigot.yml"); string(data) != testSpigotYml {
+		t.Fatal("check mode wrote the file")
+	}
+
+	if _, err := c.ConfigureServer(false); err != nil {
+		t.Fatal(err)
+	}
+
+	properties, _ := fs.ReadFile("server.properties")
+	if string(properties) != "#Minecraft server properties\nmax-players=100\nmotd=Welcome to lobby\nrcon.port=25575\n" {
+		t.Fatalf("wrong properties:\n%s", properties)
+	}
+
+	spigot, _ := fs.ReadFile("spigot.yml")
+	expected := strings.NewReplacer(
+		"  bungeecord: false\n", "  bungeecord: true\n",
+		"      max: 2048.0\n", "      max: 2048.0\n  restart-on-crash: false\n",
+		"    verbose: false\n", "    verbose: false\n    entity-tracking-range:\n      players: 64\n",
+	).Replace(testSpigotYml)
+
+	if string(spigot) != expected {
+		t.Fatalf("wrong spigot.yml:\n%s", spigot)
+	}
+
+	if changes, err := c.ConfigureServer(true); err != nil || len(changes) != 0 {
+		t.Fatalf("drift after configuring: %+v %v", changes, err)
+	}
+
+	// Keys outside of the schema are refused
+	c.LocalConfig.Server["spigot.yml"] = yaml.MapSlice{{Key: "settings.bungecord", Value: true}}
+	if _, err := c.ConfigureServer(true); err == nil || !strings.Contains(err.Error(), "settings.bungecord") {
+		t.Fatalf("expected an unknown key error, got %v", err)
+	}
+}
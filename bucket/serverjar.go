@@ -0,0 +1,357 @@
+package bucket
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Directories searched for server jars, spigot extracts
+// its real server jar in bundler/versions on first launch
+var ServerJarDirs = []string{"", "bundler/versions"}
+
+// Jar names carrying the Minecraft version, as published by the downloads
+// of paper and its forks or built by spigot's BuildTools (e.g. paper-1.20.4-496.jar)
+var ServerJarName = regexp.MustCompile(`(?i)^(?:spigot|paper|purpur|pufferfish|folia)-(\d+\.\d+(?:\.\d+)?)(?:[-.].*)?\.jar$`)
+
+// ServerJar holds the signals read from a server jar, used to detect
+// the platform without relying on the jar file name
+type ServerJar struct {
+	Path string
+
+	// Attributes of META-INF/MANIFEST.MF
+	Manifest map[string]string
+
+	// Minecraft version from version.json, patch.properties or versions.list
+	Version string
+
+	// Paperclip's patch.properties, only found in old launchers
+	Patch map[string]string
+
+	// File names of the server jars embedded in launcher jars,
+	// listed in META-INF/versions.list or found in META-INF/versions
+	Embedded []string
+
+	entries []string
+}
+
+func (j *ServerJar) MainClass() string {
+	return j.Manifest["Main-Class"]
+}
+
+// GameVersion is the Minecraft version found inside the jar or in its name
+func (j *ServerJar) GameVersion() string {
+	if j.Version != "" {
+		return j.Version
+	}
+
+	if m := ServerJarName.FindStringSubmatch(path.Base(j.Path)); m != nil {
+		return m[1]
+	}
+
+	return ""
+}
+
+// Embeds tells if the jar is a launcher for the named server (e.g. "folia")
+func (j *ServerJar) Embeds(name string) bool {
+	return slices.ContainsFunc(j.Embedded, func(e string) bool {
+		return strings.HasPrefix(e, name+"-")
+	})
+}
+
+// Patches tells if the paperclip patch properties mention the server name
+func (j *ServerJar) Patches(name string) bool {
+	for _, v := range j.Patch {
+		if strings.Contains(strings.ToLower(v), name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Launcher tells if the main class is one of the given ones
+func (j *ServerJar) Launcher(classes ...string) bool {
+	return slices.Contains(classes, j.MainClass())
+}
+
+// Contains tells if any entry path contains the slash separated fragment
+func (j *ServerJar) Contains(fragment string) bool {
+	return slices.ContainsFunc(j.entries, func(e string) bool {
+		return JarPathContains(e, fragment)
+	})
+}
+
+// ServerJars inspects the jars in the server root, once per context
+func (c *OpenContext) ServerJars() ([]*ServerJar, error) {
+	c.serverJarsOnce.Do(func() {
+		c.serverJars, c.serverJarsErr = c.inspectServerJars()
+	})
+
+	return c.serverJars, c.serverJarsErr
+}
+
+// GameVersion is the Minecraft version the server runs, the minecraft-version
+// setting takes precedence over the one detected from the server jars
+func (c *OpenContext) GameVersion() string {
+	if v := c.Config().MinecraftVersion; v != "" {
+		return v
+	}
+
+	jars, err := c.ServerJars()
+	if err != nil {
+		log.Printf("error inspecting server jars: %v\n", err)
+	}
+
+	for _, jar := range jars {
+		if v := jar.GameVersion(); v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+func (c *OpenContext) inspectServerJars() ([]*ServerJar, error) {
+	var jars []*ServerJar
+
+	for _, dir := range ServerJarDirs {
+		if ok, err := c.Fs.DirExists(dir); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+
+		files, err := c.Fs.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			if file.IsDir() || filepath.Ext(file.Name()) != ".jar" {
+				continue
+			}
+
+			jar, err := c.inspectJar(path.Join(dir, file.Name()), file.Size())
+			if err != nil {
+				// Not every jar in the root is a valid archive
+				continue
+			}
+
+			jars = append(jars, jar)
+		}
+	}
+
+	return jars, nil
+}
+
+func (c *OpenContext) inspectJar(name string, size int64) (*ServerJar, error) {
+	file, err := c.Fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	reader, err := zip.NewReader(file, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return InspectJar(name, reader)
+}
+
+func InspectJar(name string, reader *zip.Reader) (*ServerJar, error) {
+	jar := &ServerJar{Path: name, entries: make([]string, 0, len(reader.File))}
+
+	for _, f := range reader.File {
+		jar.entries = append(jar.entries, f.Name)
+
+		var err error
+		switch {
+		case f.Name == "META-INF/MANIFEST.MF":
+			err = readZipEntry(f, func(r io.Reader) (err error) {
+				jar.Manifest, err = ParseManifest(r)
+				return
+			})
+
+		case f.Name == "version.json":
+			var version struct {
+				ID string `json:"id"`
+			}
+
+			err = readZipEntry(f, func(r io.Reader) error {
+				return json.NewDecoder(r).Decode(&version)
+			})
+
+			if version.ID != "" {
+				jar.Version = version.ID
+			}
+
+		case f.Name == "patch.properties":
+			err = readZipEntry(f, func(r io.Reader) (err error) {
+				jar.Patch, err = ParseProperties(r)
+				return
+			})
+
+		case f.Name == "META-INF/versions.list":
+			err = readZipEntry(f, func(r io.Reader) error {
+				list, err := parseVersionsList(r)
+				for _, v := range list {
+					jar.Embedded = append(jar.Embedded, path.Base(v[2]))
+					if jar.Version == "" {
+						jar.Version = v[1]
+					}
+				}
+
+				return err
+			})
+
+		case strings.HasPrefix(f.Name, "META-INF/versions/") && strings.HasSuffix(f.Name, ".jar"):
+			jar.Embedded = append(jar.Embedded, path.Base(f.Name))
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if jar.Version == "" && jar.Patch != nil {
+		jar.Version = jar.Patch["version"]
+	}
+
+	jar.Embedded = slices.Compact(slices.Sorted(slices.Values(jar.Embedded)))
+
+	return jar, nil
+}
+
+func readZipEntry(f *zip.File, read func(io.Reader) error) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+
+	defer r.Close()
+
+	return read(r)
+}
+
+// ParseManifest reads the main attributes of a jar manifest,
+// continuation lines start with a single space
+func ParseManifest(r io.Reader) (map[string]string, error) {
+	attrs := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+
+	var last string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if line == "" {
+			break // End of the main section
+		}
+
+		if strings.HasPrefix(line, " ") && last != "" {
+			attrs[last] += line[1:]
+			continue
+		}
+
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			last = strings.TrimSpace(k)
+			attrs[last] = strings.TrimSpace(v)
+		}
+	}
+
+	return attrs, scanner.Err()
+}
+
+// ParseProperties reads a java properties file, without escapes support
+func ParseProperties(r io.Reader) (map[string]string, error) {
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		k, v, _ := strings.Cut(line, "=")
+		props[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+
+	return props, scanner.Err()
+}
+
+// parseVersionsList reads the "hash id path" lines of a bundler versions.list
+func parseVersionsList(r io.Reader) ([][]string, error) {
+	var res [][]string
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 3 {
+			res = append(res, fields)
+		}
+	}
+
+	return res, scanner.Err()
+}
+
+// DetectServerJar checks if any of the server jars satisfies the filter,
+// the matching jar in the server root is kept as the server jar
+func DetectServerJar(context *OpenContext, filter func(jar *ServerJar) bool) (bool, error) {
+	jars, err := context.ServerJars()
+	if err != nil {
+		return false, err
+	}
+
+	found := false
+	for _, jar := range jars {
+		if filter(jar) {
+			found = true
+
+			if path.Dir(jar.Path) == "." {
+				context.serverJar = jar
+				break
+			}
+		}
+	}
+
+	return found, nil
+}
+
+// ServerJar is the jar in the server root that launches the server,
+// as matched by the platform detector
+func (c *OpenContext) ServerJar() (*ServerJar, error) {
+	if c.serverJar == nil && c.Platform != nil {
+		// Not detected, the platform is set in the config
+		if _, err := c.Platform.Type().Detect(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.serverJar != nil {
+		return c.serverJar, nil
+	}
+
+	jars, err := c.ServerJars()
+	if err != nil {
+		return nil, err
+	}
+
+	// Some platforms aren't detected from the jar
+	for _, jar := range jars {
+		if path.Dir(jar.Path) == "." && jar.MainClass() != "" {
+			return jar, nil
+		}
+	}
+
+	return nil, errors.New("server jar not found")
+}
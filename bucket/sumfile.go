@@ -17,15 +17,23 @@ const SumDBFile string = "file"
 type SumfileDatabase struct {
 	Name string
 
-	lock    sync.Mutex
-	ctx     *OpenContext
-	plugins *SymmetricBiMap[string, CachedPlugin]
+	lock       sync.Mutex
+	ctx        *OpenContext
+	plugins    *SymmetricBiMap[string, CachedPlugin]
+	unresolved map[string]UnresolvedRecord
+}
+
+// Older sumfiles only contain the plugins array
+type sumfileContent struct {
+	Plugins    []CachedRecord     `json:"plugins"`
+	Unresolved []UnresolvedRecord `json:"unresolved"`
 }
 
 func NewNamedSumfileDatabase(name string) *SumfileDatabase {
 	return &SumfileDatabase{
-		Name:    name,
-		plugins: NewPluginBiMap(),
+		Name:       name,
+		plugins:    NewPluginBiMap(),
+		unresolved: make(map[string]UnresolvedRecord),
 	}
 }
 
@@ -61,9 +69,10 @@ func (db *SumfileDatabase) InitializeDatabase(ctx *OpenContext) error {
 			return db._parseError(err)
 		}
 
-		_, err = f.Write([]byte(SumfileHeader + "\n\n[]\n"))
+		defer f.Close()
+
+		_, err = f.Write([]byte(SumfileHeader + "\n\n{}\n"))
 		if err != nil {
-			defer f.Close()
 			return db._parseError(err)
 		}
 	}
@@ -89,19 +98,30 @@ func (db *SumfileDatabase) LoadPluginDatabase() error {
 		return db._parseError(err)
 	}
 
-	data = bytes.TrimPrefix(data, []byte(SumfileHeader))
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte(SumfileHeader)))
+
+	var content sumfileContent
+	if bytes.HasPrefix(data, []byte("[")) {
+		err = json.Unmarshal(data, &content.Plugins)
+	} else {
+		err = json.Unmarshal(data, &content)
+	}
 
-	var plugins []CachedRecord
-	if err = json.Unmarshal(data, &plugins); err != nil {
+	if err != nil {
 		return db._parseError(err)
 	}
 
-	for _, plugin := range plugins {
+	for _, rec := range content.Unresolved {
+		db.unresolved[rec.LocalIdentifier] = rec
+	}
+
+	for _, plugin := range content.Plugins {
 		plugin, err := plugin.CachedPlugin(db.ctx)
 		if err != nil {
 			return db._parseError(err)
 		}
 
+		plugin.database = db
 		db.plugins.Put(*plugin)
 	}
 
@@ -117,14 +137,23 @@ func (db *SumfileDatabase) SavePluginDatabase() error {
 	db.lock.Lock()
 	defer db.lock.Unlock()
 
-	f, err := db.ctx.Fs.OpenFile(db.Name, os.O_WRONLY|os.O_CREATE, 0644)
+	f, err := db.ctx.Fs.OpenFile(db.Name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return db._parseError(err)
 	}
 
 	defer f.Close()
 
-	data, err := json.MarshalIndent(db.plugins.Values(), "", "  ")
+	content := struct {
+		Plugins    []CachedPlugin     `json:"plugins"`
+		Unresolved []UnresolvedRecord `json:"unresolved,omitempty"`
+	}{Plugins: db.plugins.Values()}
+
+	for _, rec := range db.unresolved {
+		content.Unresolved = append(content.Unresolved, rec)
+	}
+
+	data, err := json.MarshalIndent(content, "", "  ")
 	if err != nil {
 		return db._parseError(err)
 	}
@@ -146,16 +175,53 @@ func (db *SumfileDatabase) DBSize() (int64, error) {
 }
 
 func (db *SumfileDatabase) CleanCache() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
 	if err := db.ctx.Fs.Remove(db.Name); err != nil {
 		return db._parseError(err)
 	}
 
 	db.plugins = NewPluginBiMap()
+	db.unresolved = make(map[string]UnresolvedRecord)
 
 	return nil
 }
 
+func (db *SumfileDatabase) GetUnresolved(identifier string) (UnresolvedRecord, bool) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	rec, ok := db.unresolved[identifier]
+	return rec, ok
+}
+
+func (db *SumfileDatabase) SaveUnresolved(record UnresolvedRecord) error {
+	db.lock.Lock()
+	db.unresolved[record.LocalIdentifier] = record
+	db.lock.Unlock()
+
+	return db.SavePluginDatabase()
+}
+
+func (db *SumfileDatabase) DeleteUnresolved(identifier string) error {
+	db.lock.Lock()
+	_, ok := db.unresolved[identifier]
+	delete(db.unresolved, identifier)
+	db.lock.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	return db.SavePluginDatabase()
+}
+
 func (db *SumfileDatabase) CloseDatabase() error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
 	db.plugins = nil
+	db.unresolved = nil
 	return nil
 }
@@ -0,0 +1,72 @@
+package bucket
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/MRtecno98/afero"
+)
+
+func TestSumfileLegacyFormat(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	fs.WriteFile(SumfileName, []byte(SumfileHeader+`
+
+[
+  {
+    "metadata": {},
+    "repository": "mirror",
+    "path": "plugins/Essentials.jar",
+    "name": "Essentials",
+    "local_identifier": "essentials",
+    "remote_identifier": "hXiIvTyT",
+    "confidence": 0.9
+  }
+]
+`), 0644)
+
+	c := &OpenContext{Fs: fs, LocalConfig: &Config{}, Repositories: map[string]NamedRepository{
+		"mirror": {Repository: &fakeRepository{}, RepositoryConfig: RepositoryConfig{Name: "mirror"}}}}
+
+	load := func() *SumfileDatabase {
+		db := NewSumfileDatabase()
+		if err := db.InitializeDatabase(c); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := db.LoadPluginDatabase(); err != nil {
+			t.Fatal(err)
+		}
+
+		return db
+	}
+
+	db := load()
+	if pl, ok := db.Plugins().GetFirst("essentials"); !ok || pl.RemoteIdentifier != "hXiIvTyT" {
+		t.Fatalf("legacy record not loaded: %+v", pl)
+	}
+
+	// Saving upgrades the file to the object format with the unresolved plugins
+	if err := db.SaveUnresolved(UnresolvedRecord{LocalIdentifier: "custom", Hash: "abc"}); err != nil {
+		t.Fatal(err)
+	}
+
+	db = load()
+	if _, ok := db.Plugins().GetFirst("essentials"); !ok {
+		t.Fatal("plugin lost after saving")
+	}
+
+	if rec, ok := db.GetUnresolved("custom"); !ok || rec.Hash != "abc" {
+		t.Fatalf("unresolved record lost after saving: %+v", rec)
+	}
+
+	// Versions are cached the first time they're requested online
+	pl, _ := db.Plugins().GetFirst("essentials")
+	if ids, err := pl.GetVersionIdentifiers(context.Background()); err != nil || len(ids) != 1 {
+		t.Fatalf("wrong versions: %v %v", ids, err)
+	}
+
+	if pl, _ = load().Plugins().GetFirst("essentials"); !slices.Equal(pl.Versions, []string{"hXiIvTyT-1.0"}) {
+		t.Fatalf("versions not saved: %v", pl.Versions)
+	}
+}
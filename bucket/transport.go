@@ -0,0 +1,338 @@
+package bucket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHostRate    float64 = 10
+	DefaultHostBurst   int     = 10
+	DefaultRetries     int     = 4
+	DefaultHostTimeout         = 30 * time.Second
+)
+
+var (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 60 * time.Second
+)
+
+// HostPolicy controls how requests to a single host are sent
+type HostPolicy struct {
+	// Requests per second and maximum burst of the token bucket
+	Rate  float64
+	Burst int
+
+	Retries int
+
+	// Maximum time to wait for the response headers
+	Timeout time.Duration
+}
+
+// Known API limits, used when the repository options don't override them
+var DefaultHostPolicies = map[string]HostPolicy{
+	"api.modrinth.com": {Rate: 5, Burst: 10, Retries: DefaultRetries, Timeout: DefaultHostTimeout},
+	"api.spiget.org":   {Rate: 3, Burst: 5, Retries: DefaultRetries, Timeout: DefaultHostTimeout},
+}
+
+// RetryTransport throttles requests with a token bucket per host and retries
+// failed requests with jittered exponential backoff, honoring Retry-After
+type RetryTransport struct {
+	Transport http.RoundTripper
+
+	lock     sync.Mutex
+	policies map[string]HostPolicy
+	buckets  map[string]*TokenBucket
+}
+
+type TokenBucket struct {
+	lock sync.Mutex
+
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+var sharedRetry *RetryTransport
+var sharedRetryOnce sync.Once
+
+func NewBaseTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+func NewRetryTransport(transport http.RoundTripper) *RetryTransport {
+	policies := make(map[string]HostPolicy, len(DefaultHostPolicies))
+	for h, p := range DefaultHostPolicies {
+		policies[h] = p
+	}
+
+	return &RetryTransport{
+		Transport: transport,
+		policies:  policies,
+		buckets:   make(map[string]*TokenBucket),
+	}
+}
+
+// SharedRetryTransport is the network layer shared by every repository
+func SharedRetryTransport() *RetryTransport {
+	sharedRetryOnce.Do(func() {
+		sharedRetry = NewRetryTransport(NewBaseTransport())
+	})
+
+	return sharedRetry
+}
+
+// ConfigureHost overrides the policy of the endpoint host with the
+// "rate", "burst", "retries" and "timeout" repository options
+func (t *RetryTransport) ConfigureHost(endpoint string, opts map[string]string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	policy := t.policy(u.Host)
+
+	if v, ok := opts["rate"]; ok {
+		if policy.Rate, err = strconv.ParseFloat(v, 64); err != nil {
+			return fmt.Errorf("invalid rate option: %w", err)
+		}
+	}
+
+	if v, ok := opts["burst"]; ok {
+		if policy.Burst, err = strconv.Atoi(v); err != nil {
+			return fmt.Errorf("invalid burst option: %w", err)
+		}
+	}
+
+	if v, ok := opts["retries"]; ok {
+		if policy.Retries, err = strconv.Atoi(v); err != nil {
+			return fmt.Errorf("invalid retries option: %w", err)
+		}
+	}
+
+	if v, ok := opts["timeout"]; ok {
+		if policy.Timeout, err = time.ParseDuration(v); err != nil {
+			return fmt.Errorf("invalid timeout option: %w", err)
+		}
+	}
+
+	t.policies[u.Host] = policy
+	delete(t.buckets, u.Host)
+
+	return nil
+}
+
+func (t *RetryTransport) policy(host string) HostPolicy {
+	if p, ok := t.policies[host]; ok {
+		return p
+	}
+
+	return HostPolicy{Rate: DefaultHostRate, Burst: DefaultHostBurst,
+		Retries: DefaultRetries, Timeout: DefaultHostTimeout}
+}
+
+func (t *RetryTransport) limiter(host string) (*TokenBucket, HostPolicy) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	policy := t.policy(host)
+
+	b, ok := t.buckets[host]
+	if !ok {
+		b = NewTokenBucket(policy.Rate, policy.Burst)
+		t.buckets[host] = b
+	}
+
+	return b, policy
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	bucket, policy := t.limiter(req.URL.Host)
+
+	// Requests with a body can only be retried if it can be obtained again
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	for attempt := 0; ; attempt++ {
+		if err := bucket.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+
+			req.Body = body
+		}
+
+		res, err := t.send(req, policy.Timeout)
+
+		retry, reason := shouldRetry(req, res, err)
+		if !retry || !replayable || attempt >= policy.Retries {
+			return res, err
+		}
+
+		delay := Backoff(attempt)
+		if res != nil {
+			if after, ok := RetryAfter(res); ok {
+				delay = min(max(delay, after), retryMaxDelay)
+			}
+
+			io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+			res.Body.Close()
+		}
+
+		if DEBUG {
+			log.Printf("http: %s %s failed (%s), retrying in %v [%d/%d]\n",
+				req.Method, req.URL, reason, delay.Truncate(time.Millisecond), attempt+1, policy.Retries)
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// send applies the header timeout, the body can take as long as needed
+func (t *RetryTransport) send(req *http.Request, timeout time.Duration) (*http.Response, error) {
+	if timeout <= 0 {
+		return t.Transport.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := time.AfterFunc(timeout, cancel)
+
+	res, err := t.Transport.RoundTrip(req.WithContext(ctx))
+	if !timer.Stop() && err != nil && req.Context().Err() == nil {
+		err = fmt.Errorf("timed out after %v waiting for %s: %w", timeout, req.URL.Host, err)
+	}
+
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+func shouldRetry(req *http.Request, res *http.Response, err error) (bool, string) {
+	if err != nil {
+		if req.Context().Err() != nil || errors.Is(err, ErrOffline) {
+			return false, ""
+		}
+
+		return true, err.Error()
+	}
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true, res.Status
+	}
+
+	return false, ""
+}
+
+// Backoff returns an exponential delay with full jitter in its upper half
+func Backoff(attempt int) time.Duration {
+	d := float64(retryBaseDelay) * math.Pow(2, float64(attempt))
+	d = math.Min(d, float64(retryMaxDelay))
+
+	return time.Duration(d/2 + rand.Float64()*d/2)
+}
+
+// RetryAfter parses the Retry-After header, both in seconds and HTTP date form
+func RetryAfter(res *http.Response) (time.Duration, bool) {
+	v := res.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+
+	return 0, false
+}
+
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst <= 0 {
+		burst = 1
+	}
+
+	return &TokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Wait blocks until a token is available, a non positive rate disables the limit
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	if b.rate <= 0 {
+		return nil
+	}
+
+	b.lock.Lock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	// The token is taken now, even if it's still to be generated
+	b.tokens--
+	wait := time.Duration(-b.tokens / b.rate * float64(time.Second))
+
+	b.lock.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
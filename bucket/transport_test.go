@@ -0,0 +1,87 @@
+package bucket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = 500 * time.Millisecond }()
+
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if requests < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	defer srv.Close()
+
+	client := &http.Client{Transport: NewRetryTransport(http.DefaultTransport)}
+
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK || requests != 3 {
+		t.Fatalf("expected success after 3 requests, got %d after %d", res.StatusCode, requests)
+	}
+}
+
+func TestRetryTransportGivesUp(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = 500 * time.Millisecond }()
+
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	defer srv.Close()
+
+	transport := NewRetryTransport(http.DefaultTransport)
+	if err := transport.ConfigureHost(srv.URL, map[string]string{"retries": "2", "rate": "0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := (&http.Client{Transport: transport}).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusServiceUnavailable || requests != 3 {
+		t.Fatalf("expected 3 attempts ending in 503, got %d after %d", res.StatusCode, requests)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	b := NewTokenBucket(100, 2)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := b.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Two tokens from the burst, two generated at 100/s
+	if el := time.Since(start); el < 15*time.Millisecond {
+		t.Fatalf("token bucket didn't throttle, took %v", el)
+	}
+}
@@ -0,0 +1,183 @@
+package bucket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Where the replaced server jar is kept until the next upgrade
+const RollbackFolder = ".bucket/rollback"
+
+// Holds the name of the platform the rollback jar belongs to
+const rollbackPlatformFile = "platform"
+
+var ErrNoRollback = errors.New("no rollback point available")
+
+// UpgradeIssue is an installed plugin that has to be
+// upgraded (or removed) before switching the server
+type UpgradeIssue struct {
+	Plugin Plugin
+	Reason string
+}
+
+func (i UpgradeIssue) String() string {
+	return i.Plugin.GetName() + ": " + i.Reason
+}
+
+// PluginUpdate is a newer version of a plugin available for the target,
+// the installed one isn't known to break so it doesn't block the upgrade
+type PluginUpdate struct {
+	Plugin  Plugin
+	Version string
+	Latest  string
+}
+
+func (u PluginUpdate) String() string {
+	return u.Plugin.GetName() + ": " + u.Version + " -> " + u.Latest
+}
+
+// UpgradeCheck is the result of CheckUpgrade, only issues block the upgrade
+type UpgradeCheck struct {
+	Issues  []UpgradeIssue
+	Updates []PluginUpdate
+}
+
+// CheckUpgrade lists the installed plugins that wouldn't work on the target
+// platform and Minecraft version, and the ones with a newer version for it
+func (c *OpenContext) CheckUpgrade(ctx context.Context, target PlatformType, version string) (*UpgradeCheck, error) {
+	plugins, _, err := c.Platform.Plugins()
+	if err != nil && len(plugins) == 0 {
+		return nil, err
+	}
+
+	check := &UpgradeCheck{}
+	for _, pl := range plugins {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if !target.Supports(pl) {
+			check.Issues = append(check.Issues, UpgradeIssue{pl, "not supported on " + target.Name})
+			continue
+		}
+
+		cached, ok := c.Plugins().GetFirst(pl.GetIdentifier())
+		if !ok {
+			// Unresolved plugins can only be checked against the current platform
+			if !target.AnyCompatible(c.Platform.Type().EveryCompatible()) {
+				check.Issues = append(check.Issues, UpgradeIssue{pl, "unresolved and " +
+					c.PlatformName() + " plugins don't run on " + target.Name})
+			}
+
+			continue
+		}
+
+		latest, err := cached.GetLatestCompatibleFor(ctx, target, version)
+		if err != nil {
+			check.Issues = append(check.Issues, UpgradeIssue{pl, fmt.Sprintf("no compatible version on %s: %v",
+				cached.Repository.GetName(), err)})
+			continue
+		}
+
+		if v, ok := pl.(Versionable); ok && !sameVersion(v.GetVersion(), latest.GetVersion()) {
+			check.Updates = append(check.Updates, PluginUpdate{pl, v.GetVersion(), latest.GetVersion()})
+		}
+	}
+
+	return check, nil
+}
+
+func sameVersion(a, b string) bool {
+	return strings.EqualFold(strings.TrimPrefix(a, "v"), strings.TrimPrefix(b, "v"))
+}
+
+// UpgradeServer replaces the server jar with the target platform build,
+// the old jar is kept in the rollback folder and restored if the install fails
+func (c *OpenContext) UpgradeServer(target PlatformType, version string) error {
+	jar, err := c.ServerJar()
+	if err != nil {
+		return err
+	}
+
+	if err := c.Fs.RemoveAll(RollbackFolder); err != nil {
+		return err
+	}
+
+	if err := c.Fs.MkdirAll(RollbackFolder, 0755); err != nil {
+		return err
+	}
+
+	backup := path.Join(RollbackFolder, path.Base(jar.Path))
+	if err := c.Fs.Rename(jar.Path, backup); err != nil {
+		return err
+	}
+
+	if err := c.Fs.WriteFile(path.Join(RollbackFolder, rollbackPlatformFile),
+		[]byte(c.PlatformName()+"\n"), 0644); err != nil {
+		return err
+	}
+
+	if err := target.Install(c, version); err != nil {
+		if rerr := c.Fs.Rename(backup, jar.Path); rerr != nil {
+			return fmt.Errorf("%w (restoring %s failed: %v)", err, jar.Path, rerr)
+		}
+
+		return err
+	}
+
+	return c.pinPlatform(target.Name)
+}
+
+// RollbackServer puts back the jar replaced by the last upgrade
+func (c *OpenContext) RollbackServer() error {
+	files, err := c.Fs.ReadDir(RollbackFolder)
+	if err != nil {
+		return ErrNoRollback
+	}
+
+	var backup string
+	for _, f := range files {
+		if !f.IsDir() && path.Ext(f.Name()) == ".jar" {
+			backup = f.Name()
+		}
+	}
+
+	if backup == "" {
+		return ErrNoRollback
+	}
+
+	platform, err := c.Fs.ReadFile(path.Join(RollbackFolder, rollbackPlatformFile))
+	if err != nil {
+		return err
+	}
+
+	if jar, err := c.ServerJar(); err == nil {
+		if err := c.Fs.Remove(jar.Path); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Fs.Rename(path.Join(RollbackFolder, backup), backup); err != nil {
+		return err
+	}
+
+	if err := c.pinPlatform(strings.TrimSpace(string(platform))); err != nil {
+		return err
+	}
+
+	return c.Fs.RemoveAll(RollbackFolder)
+}
+
+// pinPlatform updates the platform in the local config, if one is set
+func (c *OpenContext) pinPlatform(name string) error {
+	conf, err := LoadFilesystemConfig(c.Fs, ConfigName)
+	if err != nil || conf == nil || conf.Platform == "" || conf.Platform == name {
+		return err
+	}
+
+	conf.Platform = name
+	return WriteFilesystemConfig(c.Fs, ConfigName, conf)
+}
@@ -0,0 +1,170 @@
+package bucket
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MRtecno98/afero"
+)
+
+func launcherJar(t *testing.T, main string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+
+	f, err := w.Create("META-INF/MANIFEST.MF")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.Write([]byte("Main-Class: " + main + "\r\n\r\n"))
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestUpgradeServer(t *testing.T) {
+	fs := afero.Afero{Fs: afero.NewMemMapFs()}
+	fs.WriteFile("old-server.jar", launcherJar(t, "com.example.Old"), 0644)
+	fs.WriteFile(ConfigName, []byte("platform: old\n"), 0644)
+
+	var failure error
+	target := PlatformType{Name: "new", Install: func(c *OpenContext, version string) error {
+		if failure != nil {
+			return failure
+		}
+
+		return c.Fs.WriteFile("new-server-"+version+".jar", launcherJar(t, "com.example.New"), 0644)
+	}}
+
+	old := PlatformType{Name: "old", Detect: func(*OpenContext) (Platform, error) { return nil, nil }}
+	open := func() *OpenContext {
+		c := &OpenContext{Fs: fs, Lock: context.Background(), LocalConfig: &Config{}}
+		c.Platform = &stubPlatform{old}
+		return c
+	}
+
+	// A failed install puts back the old jar
+	failure = errors.New("download failed")
+	if err := open().UpgradeServer(target, "1.21.1"); !errors.Is(err, failure) {
+		t.Fatalf("expected the install error, got %v", err)
+	}
+
+	if ok, _ := fs.Exists("old-server.jar"); !ok {
+		t.Fatal("old jar not restored after a failed install")
+	}
+
+	failure = nil
+	if err := open().UpgradeServer(target, "1.21.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := fs.Exists("new-server-1.21.1.jar"); !ok {
+		t.Fatal("new jar not installed")
+	}
+
+	if ok, _ := fs.Exists(RollbackFolder + "/old-server.jar"); !ok {
+		t.Fatal("old jar not kept for rollback")
+	}
+
+	if conf, _ := LoadFilesystemConfig(fs, ConfigName); conf.Platform != "new" {
+		t.Fatalf("platform not updated in the config: %s", conf.Platform)
+	}
+
+	if err := open().RollbackServer(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, exists := range map[string]bool{
+		"old-server.jar": true, "new-server-1.21.1.jar": false, RollbackFolder: false} {
+		if ok, _ := fs.Exists(name); ok != exists {
+			t.Errorf("%s: expected exists=%v after the rollback", name, exists)
+		}
+	}
+
+	if conf, _ := LoadFilesystemConfig(fs, ConfigName); conf.Platform != "old" {
+		t.Fatalf("platform not restored in the config: %s", conf.Platform)
+	}
+
+	if err := open().RollbackServer(); !errors.Is(err, ErrNoRollback) {
+		t.Fatalf("expected no rollback point, got %v", err)
+	}
+}
+
+// stubPlatform is a platform without plugins
+type stubPlatform struct {
+	platform PlatformType
+}
+
+func (p *stubPlatform) Type() PlatformType                      { return p.platform }
+func (p *stubPlatform) GameVersion() string                     { return "" }
+func (p *stubPlatform) PluginsFolder() string                   { return "plugins" }
+func (p *stubPlatform) Plugins() ([]Plugin, []error, error)     { return nil, nil, nil }
+func (p *stubPlatform) LoadPlugin(string) (*LocalPlugin, error) { return nil, nil }
+
+// gameReleases is a remote plugin with its latest version for each Minecraft version
+type gameReleases struct {
+	RemoteVersion
+	latest map[string]string
+}
+
+type remoteRelease struct {
+	RemoteVersion
+	version string
+}
+
+func (r remoteRelease) GetVersion() string { return r.version }
+
+func (r *gameReleases) GetLatestCompatibleFor(ctx context.Context, plt PlatformType, game string) (RemoteVersion, error) {
+	if v, ok := r.latest[game]; ok {
+		return remoteRelease{version: v}, nil
+	}
+
+	return nil, errors.New("not available for " + game)
+}
+
+func TestCheckUpgrade(t *testing.T) {
+	plt := &pluginsPlatform{stubPlatform: stubPlatform{PlatformType{Name: "paper"}}, plugins: []Plugin{
+		&LocalPlugin{PluginDescriptor: versionedDescriptor{testDescriptor{"Essentials"}, "2.20.1"}},
+		&LocalPlugin{PluginDescriptor: versionedDescriptor{testDescriptor{"LuckPerms"}, "5.4"}},
+		&LocalPlugin{PluginDescriptor: versionedDescriptor{testDescriptor{"Abandoned"}, "1.0"}},
+		&LocalPlugin{PluginDescriptor: testDescriptor{"Unresolved"}},
+	}}
+
+	conf := &Config{MinecraftVersion: "1.20.4"}
+	c := &OpenContext{Platform: plt, PluginDatabase: NewSqliteDatabase(), LocalConfig: conf}
+
+	resolved := map[string]map[string]string{
+		"essentials": {"1.20.4": "2.20.1", "1.21.1": "2.21.0"},
+		"luckperms":  {"1.20.4": "5.4", "1.21.1": "5.4"},
+		"abandoned":  {"1.20.4": "1.0"},
+	}
+
+	for id, latest := range resolved {
+		c.Plugins().Put(CachedPlugin{RemotePlugin: &gameReleases{latest: latest}, requested: true,
+			CachedRecord: CachedRecord{LocalIdentifier: id, RemoteIdentifier: id}})
+	}
+
+	check, err := c.CheckUpgrade(context.Background(), plt.Type(), "1.21.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Newer versions are only reported, missing ones block the upgrade
+	if len(check.Updates) != 1 || check.Updates[0].String() != "Essentials: 2.20.1 -> 2.21.0" {
+		t.Fatalf("wrong updates: %v", check.Updates)
+	}
+
+	if len(check.Issues) != 1 || check.Issues[0].Plugin.GetName() != "Abandoned" {
+		t.Fatalf("wrong issues: %v", check.Issues)
+	}
+
+	if conf.MinecraftVersion != "1.20.4" {
+		t.Fatalf("configured version changed to %s", conf.MinecraftVersion)
+	}
+}
@@ -3,6 +3,9 @@ package bucket
 import (
 	"archive/zip"
 	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
 	"slices"
 	"sync"
 
@@ -26,6 +29,20 @@ func OpenJar(file afero.File) (*afero.Afero, error) {
 	return &afero.Afero{Fs: zipfs.New(reader)}, nil
 }
 
+// HashFile computes the hex encoded sha256 of a file from its beginning
+func HashFile(file afero.File) (string, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 func Decamel(camel string, sep string) string {
 	var result string
 	for i, r := range camel {
@@ -2,7 +2,6 @@ package cli
 
 import (
 	"fmt"
-	"io"
 	"log"
 	"os"
 
@@ -26,6 +25,10 @@ var ADD = &cli.Command{
 			return cli.Exit("missing plugin name", 1)
 		}
 
+		if err := bucket.RequireOnline("installing plugins"); err != nil {
+			return cli.Exit(err, 1)
+		}
+
 		return Workspace.RunWithContext("add", func(oc *bucket.OpenContext, log *log.Logger) error {
 			const REPO = "modrinth"
 
@@ -33,7 +36,7 @@ var ADD = &cli.Command{
 				return cli.Exit("no platform set", 1)
 			}
 
-			res, _, err := oc.Repositories[REPO].Search(c.Args().Get(0), 5)
+			res, _, err := oc.Repositories[REPO].Search(oc.Lock, c.Args().Get(0), 5)
 			if err != nil {
 				return err
 			}
@@ -60,14 +63,14 @@ var ADD = &cli.Command{
 			log.Printf("Selected %s\n\n", options[n])
 
 			pl := res[n]
-			ver, err := pl.GetLatestVersion()
+			ver, err := pl.GetLatestVersion(oc.Lock)
 			if err != nil {
 				return err
 			}
 
 			log.Printf("Installing %s [%s]\n", pl.GetName(), ver.GetIdentifier())
 
-			files, err := ver.GetFiles()
+			files, err := ver.GetFiles(oc.Lock)
 			if err != nil {
 				return err
 			}
@@ -79,29 +82,7 @@ var ADD = &cli.Command{
 				if !f.Optional() {
 					log.Printf("Downloading %s\n", f.Name())
 
-					data, err := f.Download()
-					if err != nil {
-						return err
-					}
-
-					defer data.Close()
-
-					fd, err := folder.OpenFile(f.Name(), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-					if err != nil {
-						return err
-					}
-
-					defer fd.Close()
-
-					if _, err := io.Copy(fd, data); err != nil {
-						return err
-					}
-
-					if err := fd.Close(); err != nil {
-						return err
-					}
-
-					if err := f.Verify(); err != nil {
+					if err := bucket.DownloadFile(oc.Lock, folder, f); err != nil {
 						return err
 					} else {
 						log.Printf("File %s verified\n", f.Name())
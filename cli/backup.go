@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/MRtecno98/bucket/bucket"
+	"github.com/urfave/cli/v2"
+)
+
+var BACKUP = &cli.Command{
+	Name:    "backup",
+	Aliases: []string{"b"},
+	Usage:   "snapshots worlds and configs",
+
+	Subcommands: []*cli.Command{
+		{
+			Name:   "create",
+			Usage:  "snapshots the worlds, the plugin configs and the server configs",
+			Before: InitializeContexts(false),
+			After:  ShutdownContexts,
+
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "prune",
+					Usage: "prunes the old snapshots afterwards",
+				},
+			},
+
+			Action: func(c *cli.Context) error {
+				return Workspace.RunWithContext("backup", func(oc *bucket.OpenContext, log *log.Logger) error {
+					backup, err := oc.CreateBackup(oc.Lock)
+					if err != nil {
+						return err
+					}
+
+					log.Printf("created backup %s (%d files, %.2f MB, %.2f MB stored)\n", backup.ID,
+						len(backup.Files), megabytes(backup.TotalSize()), megabytes(backup.Stored))
+
+					if c.Bool("prune") {
+						return pruneBackups(oc, log)
+					}
+
+					return nil
+				})
+			},
+		},
+		{
+			Name:    "list",
+			Aliases: []string{"ls"},
+			Usage:   "lists the snapshots, newest first",
+			Before:  InitializeContexts(false),
+			After:   ShutdownContexts,
+
+			Action: func(c *cli.Context) error {
+				return Workspace.RunWithContext("backups", func(oc *bucket.OpenContext, log *log.Logger) error {
+					backups, err := oc.Backups()
+					if err != nil {
+						return err
+					}
+
+					if len(backups) == 0 {
+						log.Println("no backups")
+					}
+
+					for _, b := range backups {
+						log.Printf("%s  %s  %s %s  %d files  %.2f MB (%.2f MB stored)\n", b.ID,
+							b.Created.Local().Format(time.DateTime), b.Platform, b.GameVersion,
+							len(b.Files), megabytes(b.TotalSize()), megabytes(b.Stored))
+					}
+
+					return nil
+				})
+			},
+		},
+		{
+			Name:      "restore",
+			Usage:     "replaces the worlds and configs with a snapshot",
+			Before:    InitializeContexts(false),
+			After:     ShutdownContexts,
+			Args:      true,
+			ArgsUsage: " id",
+
+			Action: func(c *cli.Context) error {
+				if c.Args().Len() == 0 {
+					return cli.Exit("missing backup id", 1)
+				}
+
+				return Workspace.RunWithContext("restore", func(oc *bucket.OpenContext, log *log.Logger) error {
+					if err := oc.RestoreBackup(oc.Lock, c.Args().Get(0)); err != nil {
+						return err
+					}
+
+					log.Printf("restored backup %s\n", c.Args().Get(0))
+					return nil
+				})
+			},
+		},
+		{
+			Name:   "verify",
+			Usage:  "checks every chunk in the backup store",
+			Before: InitializeContexts(false),
+			After:  ShutdownContexts,
+
+			Action: func(c *cli.Context) error {
+				return Workspace.RunWithContext("verify", func(oc *bucket.OpenContext, log *log.Logger) error {
+					checked, problems, err := oc.VerifyBackups(oc.Lock)
+					if err != nil {
+						return err
+					}
+
+					for _, p := range problems {
+						log.Printf("error: %v\n", p)
+					}
+
+					if len(problems) > 0 {
+						return cli.Exit(fmt.Sprintf("%d problems in %d chunks", len(problems), checked), 1)
+					}
+
+					log.Printf("verified %d chunks\n", checked)
+					return nil
+				})
+			},
+		},
+		{
+			Name:   "prune",
+			Usage:  "deletes the snapshots outside of the retention policy",
+			Before: InitializeContexts(false),
+			After:  ShutdownContexts,
+
+			Action: func(c *cli.Context) error {
+				return Workspace.RunWithContext("prune", func(oc *bucket.OpenContext, log *log.Logger) error {
+					return pruneBackups(oc, log)
+				})
+			},
+		},
+	},
+}
+
+func megabytes(size int64) float64 {
+	return float64(size) / 1024 / 1024
+}
+
+func pruneBackups(oc *bucket.OpenContext, log *log.Logger) error {
+	pruned, err := oc.PruneBackups()
+	if err != nil {
+		return err
+	}
+
+	for _, b := range pruned {
+		log.Printf("deleted backup %s\n", b.ID)
+	}
+
+	daily, weekly := oc.Config().BackupRetention()
+	log.Printf("pruned %d backups (keeping %d daily, %d weekly)\n", len(pruned), daily, weekly)
+
+	return nil
+}
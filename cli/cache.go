@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/MRtecno98/afero"
+	"github.com/MRtecno98/bucket/bucket"
+	"github.com/urfave/cli/v2"
+)
+
+var CACHE = &cli.Command{
+	Name:  "cache",
+	Usage: "manages the shared HTTP response cache",
+	Before: func(c *cli.Context) error {
+		bucket.LoadSystemConfig(afero.NewOsFs(), c.String("config"))
+		return nil
+	},
+
+	Subcommands: []*cli.Command{
+		{
+			Name:  "clear",
+			Usage: "deletes every cached response",
+			After: ShutdownContexts,
+			Action: func(c *cli.Context) error {
+				cache := bucket.SharedHTTPCache()
+
+				stats, err := cache.Stats()
+				if err != nil {
+					return err
+				}
+
+				fmt.Printf("deleting %d cached responses (%.2f MB)\n",
+					stats.Entries, float64(stats.Size)/1024/1024)
+
+				return cache.Clear()
+			},
+		},
+		{
+			Name:  "stats",
+			Usage: "prints cache usage",
+			After: ShutdownContexts,
+			Action: func(c *cli.Context) error {
+				stats, err := bucket.SharedHTTPCache().Stats()
+				if err != nil {
+					return err
+				}
+
+				fmt.Printf("Location: %s\n", bucket.CacheDirectory())
+				fmt.Printf("Entries: %d\n", stats.Entries)
+				fmt.Printf("Size: %.2f MB / %.2f MB\n",
+					float64(stats.Size)/1024/1024, float64(stats.MaxSize)/1024/1024)
+
+				return nil
+			},
+		},
+	},
+}
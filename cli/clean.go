@@ -27,6 +27,12 @@ var CLEAN = &cli.Command{
 
 			if c.Args().Len() > 0 {
 				if c.Args().Get(0) == "all" {
+					if err := bucket.SharedHTTPCache().Clear(); err != nil {
+						return err
+					}
+
+					log.Println("deleted HTTP cache")
+
 					var size int64
 
 					if size, err = oc.PluginsSize(); err != nil {
@@ -20,7 +20,7 @@ var GlobalError error
 var Time time.Time
 
 var Commands = []*cli.Command{
-	ADD, CLEAN, DEBUG, // LIST, REMOVE, RUN, SEARCH, UPDATE,
+	ADD, BACKUP, CACHE, CLEAN, CONFIG, DEBUG, DIFF, INIT, LIST, MIRROR, PACK, PROXY, SECRET, SERVER, UNPACK, // REMOVE, RUN, SEARCH, UPDATE,
 }
 
 func InitializeContexts(loadDatabase bool) func(*cli.Context) error {
@@ -51,7 +51,7 @@ func InitializeContexts(loadDatabase bool) func(*cli.Context) error {
 		}
 
 		var err error
-		Workspace, err = bucket.GlobalConfig.MakeWorkspace()
+		Workspace, err = bucket.GlobalConfig.MakeWorkspace(c.Context)
 
 		if err != nil {
 			log.Print("failed to initialize workspace: ", err)
@@ -75,6 +75,15 @@ func InitializeContexts(loadDatabase bool) func(*cli.Context) error {
 	}
 }
 
+// ExitCode is the exit status of the process after running a command
+func ExitCode() int {
+	if GlobalError != nil {
+		return 1
+	}
+
+	return 0
+}
+
 func ShutdownContexts(c *cli.Context) error {
 	if Workspace != nil {
 		Workspace.CloseWorkspace()
@@ -88,7 +97,7 @@ func ShutdownContexts(c *cli.Context) error {
 	dur := time.Since(Time).Truncate(time.Millisecond)
 
 	if GlobalError != nil {
-		fmt.Print(GlobalError.Error())
+		fmt.Print(bucket.MaskSecrets(GlobalError.Error()))
 		fmt.Printf("FAILURE (took %v)\n", dur)
 	} else {
 		fmt.Printf("SUCCESS (took %v)\n", dur)
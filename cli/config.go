@@ -0,0 +1,325 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/MRtecno98/bucket/bucket"
+	"github.com/urfave/cli/v2"
+)
+
+var CONFIG = &cli.Command{
+	Name:    "config",
+	Aliases: []string{"cfg"},
+	Usage:   "tracks the history of plugin configs and renders the config overlays",
+
+	Subcommands: []*cli.Command{
+		{
+			Name:    "list",
+			Aliases: []string{"ls"},
+			Usage:   "lists the plugin config folders and their last snapshot",
+			Before:  InitializeContexts(false),
+			After:   ShutdownContexts,
+
+			Action: func(c *cli.Context) error {
+				return Workspace.RunWithContext("configs", func(oc *bucket.OpenContext, log *log.Logger) error {
+					tracked, err := oc.TrackedConfigs()
+					if err != nil {
+						return err
+					}
+
+					for _, t := range tracked {
+						last := "never snapshotted"
+						if snap, err := oc.FindConfigSnapshot(t.Name(), ""); err == nil {
+							last = snap.ID + "  " + snap.Created.Local().Format(time.DateTime)
+						}
+
+						log.Printf("%s  %s  (%s)\n", t.Name(), t.Folder, last)
+					}
+
+					return nil
+				})
+			},
+		},
+		{
+			Name:      "snapshot",
+			Usage:     "saves the configs of the plugins, all of them if none is given",
+			Before:    InitializeContexts(false),
+			After:     ShutdownContexts,
+			Args:      true,
+			ArgsUsage: " [plugin...]",
+
+			Action: func(c *cli.Context) error {
+				return Workspace.RunWithContext("snapshot", func(oc *bucket.OpenContext, log *log.Logger) error {
+					tracked, err := trackedConfigs(oc, c.Args().Slice())
+					if err != nil {
+						return err
+					}
+
+					saved := 0
+					for _, t := range tracked {
+						snap, err := oc.SnapshotConfig(t)
+						if err != nil {
+							return err
+						}
+
+						if snap != nil {
+							log.Printf("%s: snapshot %s (%d files)\n", t.Name(), snap.ID, len(snap.Files))
+							saved++
+						}
+					}
+
+					log.Printf("%d of %d plugin configs changed\n", saved, len(tracked))
+					return nil
+				})
+			},
+		},
+		{
+			Name:      "log",
+			Usage:     "lists the config snapshots of a plugin, newest first",
+			Before:    InitializeContexts(false),
+			After:     ShutdownContexts,
+			Args:      true,
+			ArgsUsage: " plugin",
+
+			Action: func(c *cli.Context) error {
+				if c.Args().Len() == 0 {
+					return cli.Exit("missing plugin", 1)
+				}
+
+				return Workspace.RunWithContext("log", func(oc *bucket.OpenContext, log *log.Logger) error {
+					history, err := oc.ConfigLog(c.Args().Get(0))
+					if err != nil {
+						return err
+					}
+
+					if len(history) == 0 {
+						log.Println("no snapshots")
+					}
+
+					for _, s := range history {
+						log.Printf("%s  %s  %d files\n", s.ID,
+							s.Created.Local().Format(time.DateTime), len(s.Files))
+					}
+
+					return nil
+				})
+			},
+		},
+		{
+			Name:      "diff",
+			Usage:     "compares the configs of a plugin with a snapshot or another context",
+			Before:    InitializeContexts(false),
+			After:     ShutdownContexts,
+			Args:      true,
+			ArgsUsage: " plugin",
+
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "snapshot",
+					Usage: "compares with the snapshot `ID`, the latest if unset",
+				},
+				&cli.StringFlag{
+					Name:  "with",
+					Usage: "compares with the configs in `CONTEXT`, by name or URL",
+				},
+			},
+
+			Action: func(c *cli.Context) error {
+				if c.Args().Len() == 0 {
+					return cli.Exit("missing plugin", 1)
+				}
+
+				plugin := c.Args().Get(0)
+				return Workspace.RunWithContext("diff", func(oc *bucket.OpenContext, log *log.Logger) error {
+					oc.LoadSecretMasks()
+
+					t, err := oc.TrackedConfig(plugin)
+					if err != nil {
+						return err
+					}
+
+					current, err := oc.ConfigFiles(t.Folder)
+					if err != nil {
+						return err
+					}
+
+					var base map[string][]byte
+					var label string
+
+					if with := c.String("with"); with != "" {
+						other, err := bucket.GlobalConfig.FindContext(with).OpenShallow(oc.Lock)
+						if err != nil {
+							return err
+						}
+
+						defer other.CloseContext()
+
+						ot, err := other.TrackedConfig(plugin)
+						if err != nil {
+							return err
+						}
+
+						if base, err = other.ConfigFiles(ot.Folder); err != nil {
+							return err
+						}
+
+						label = with
+					} else {
+						snap, err := oc.FindConfigSnapshot(t.Name(), c.String("snapshot"))
+						if err != nil {
+							return err
+						}
+
+						if base, err = oc.SnapshotFiles(snap); err != nil {
+							return err
+						}
+
+						label = snap.ID
+					}
+
+					changes := bucket.DiffConfigFiles(base, current)
+					for _, ch := range changes {
+						logConfigChange(log, ch, label, "current")
+					}
+
+					log.Printf("%d files changed\n", len(changes))
+					return nil
+				})
+			},
+		},
+		{
+			Name:      "revert",
+			Usage:     "puts back the configs of a plugin from a snapshot, the latest if unset",
+			Before:    InitializeContexts(false),
+			After:     ShutdownContexts,
+			Args:      true,
+			ArgsUsage: " plugin [id]",
+
+			Action: func(c *cli.Context) error {
+				if c.Args().Len() == 0 {
+					return cli.Exit("missing plugin", 1)
+				}
+
+				return Workspace.RunWithContext("revert", func(oc *bucket.OpenContext, log *log.Logger) error {
+					t, err := oc.TrackedConfig(c.Args().Get(0))
+					if err != nil {
+						return err
+					}
+
+					snap, err := oc.RevertConfig(t, c.Args().Get(1))
+					if err != nil {
+						return err
+					}
+
+					log.Printf("reverted %s configs to %s\n", t.Name(), snap.ID)
+					return nil
+				})
+			},
+		},
+		{
+			Name:   "apply",
+			Usage:  "renders the config overlays in the context",
+			Before: InitializeContexts(false),
+			After:  ShutdownContexts,
+
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "force",
+					Usage: "overwrites the files edited by hand too",
+				},
+			},
+
+			Action: func(c *cli.Context) error {
+				return Workspace.RunWithContext("apply", func(oc *bucket.OpenContext, log *log.Logger) error {
+					result, err := oc.ApplyOverlays(c.Bool("force"))
+					if err != nil {
+						return err
+					}
+
+					for _, name := range result.Written {
+						log.Printf("wrote %s\n", name)
+					}
+
+					for _, name := range result.Skipped {
+						log.Printf("warn: %s was edited by hand, skipped (use --force)\n", name)
+					}
+
+					if len(result.Unknown) > 0 {
+						log.Printf("warn: no value for %s\n", strings.Join(result.Unknown, ", "))
+					}
+
+					log.Printf("%d files written, %d unchanged, %d skipped\n",
+						len(result.Written), result.Unchanged, len(result.Skipped))
+					return nil
+				})
+			},
+		},
+		{
+			Name:   "drift",
+			Usage:  "reports the overlay files edited on the server or not applied yet",
+			Before: InitializeContexts(false),
+			After:  ShutdownContexts,
+
+			Action: func(c *cli.Context) error {
+				return Workspace.RunWithContext("drift", func(oc *bucket.OpenContext, log *log.Logger) error {
+					drift, err := oc.OverlayDrift()
+					if err != nil {
+						return err
+					}
+
+					for _, d := range drift {
+						log.Printf("%-8s %s\n", d.Status, d.Path)
+					}
+
+					if len(drift) > 0 {
+						return cli.Exit(fmt.Sprintf("%d files drifted", len(drift)), 1)
+					}
+
+					log.Println("no drift")
+					return nil
+				})
+			},
+		},
+	},
+}
+
+// trackedConfigs finds the config folders of the plugins, all if none is given
+func trackedConfigs(oc *bucket.OpenContext, plugins []string) ([]bucket.TrackedConfig, error) {
+	if len(plugins) == 0 {
+		return oc.TrackedConfigs()
+	}
+
+	tracked := make([]bucket.TrackedConfig, 0, len(plugins))
+	for _, name := range plugins {
+		t, err := oc.TrackedConfig(name)
+		if err != nil {
+			return nil, err
+		}
+
+		tracked = append(tracked, t)
+	}
+
+	return tracked, nil
+}
+
+func logConfigChange(log *log.Logger, ch bucket.ConfigChange, from, to string) {
+	fromName, toName := ch.Path+" ("+from+")", ch.Path+" ("+to+")"
+	if ch.Old == nil {
+		fromName = "/dev/null"
+	}
+
+	if ch.New == nil {
+		toName = "/dev/null"
+	}
+
+	if diff := bucket.UnifiedDiff(fromName, toName, ch.Old, ch.New); diff != "" {
+		log.Print(diff)
+	} else if ch.Old == nil {
+		log.Printf("added empty %s\n", ch.Path)
+	} else {
+		log.Printf("removed empty %s\n", ch.Path)
+	}
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/MRtecno98/afero"
+	"github.com/MRtecno98/bucket/bucket"
+	"github.com/urfave/cli/v2"
+)
+
+var DIFF = &cli.Command{
+	Name:      "diff",
+	Usage:     "compares the plugins, versions and configs of two servers",
+	Args:      true,
+	ArgsUsage: " context-a context-b",
+	Before: func(c *cli.Context) error {
+		if c.Args().Len() != 2 {
+			return cli.Exit("expected two contexts, by name or URL", 1)
+		}
+
+		bucket.LoadSystemConfig(afero.NewOsFs(), c.String("config"))
+
+		var err error
+		Workspace, err = bucket.CreateWorkspace(c.Context,
+			bucket.GlobalConfig.FindContext(c.Args().Get(0)),
+			bucket.GlobalConfig.FindContext(c.Args().Get(1)))
+		if err != nil {
+			return err
+		}
+
+		for _, oc := range Workspace.Contexts {
+			if err := oc.LoadPluginDatabase(); err != nil {
+				return fmt.Errorf("failed to load database for %s: %v", oc.Name, err)
+			}
+		}
+
+		return nil
+	},
+	After: ShutdownContexts,
+	Action: func(c *cli.Context) error {
+		a, b := Workspace.Contexts[0], Workspace.Contexts[1]
+		a.LoadSecretMasks()
+		b.LoadSecretMasks()
+
+		err, _ := a.Run("diff", func(a *bucket.OpenContext, log *log.Logger) error {
+			diff, err := bucket.DiffContexts(a, b)
+			if err != nil {
+				return err
+			}
+
+			log.Printf("--- %s\n+++ %s\n", a.Name, b.Name)
+			if diff.Empty() {
+				log.Println("no differences")
+				return nil
+			}
+
+			if a.PlatformName() != b.PlatformName() || a.GameVersion() != b.GameVersion() {
+				log.Printf("platform: %s %s -> %s %s\n",
+					a.PlatformName(), a.GameVersion(), b.PlatformName(), b.GameVersion())
+			}
+
+			for _, pd := range diff.Plugins {
+				log.Println(pluginDiffLine(pd))
+			}
+
+			files := 0
+			for _, cd := range diff.Configs {
+				for _, ch := range cd.Changes {
+					ch.Path = cd.Plugin + "/" + ch.Path
+					logConfigChange(log, ch, a.Name, b.Name)
+					files++
+				}
+			}
+
+			log.Printf("%d plugins and %d config files differ\n", len(diff.Plugins), files)
+			return nil
+		})
+
+		return err
+	},
+}
+
+func pluginDiffLine(pd bucket.PluginDiff) string {
+	switch {
+	case pd.B == nil:
+		return "- " + pd.A.Name + " " + pd.A.Version
+	case pd.A == nil:
+		return "+ " + pd.B.Name + " " + pd.B.Version
+	case pd.VersionChanged():
+		return "~ " + pd.A.Name + " " + pd.A.Version + " -> " + pd.B.Version
+	default:
+		return "~ " + pd.A.Name + " " + pd.A.Version + " (different jar)"
+	}
+}
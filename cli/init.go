@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"log"
+
+	"github.com/MRtecno98/bucket/bucket"
+	"github.com/urfave/cli/v2"
+)
+
+const EulaFile = "eula.txt"
+
+var INIT = &cli.Command{
+	Name:   "init",
+	Usage:  "downloads a new server and pins its platform",
+	Before: InitializeContexts(false),
+	After:  ShutdownContexts,
+
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "platform",
+			Usage:    "installs the `PLATFORM` server",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "version",
+			Usage: "installs the server for `VERSION`, latest if unset",
+		},
+		&cli.BoolFlag{
+			Name:  "accept-eula",
+			Usage: "accepts the Minecraft EULA (https://aka.ms/MinecraftEULA)",
+		},
+	},
+
+	Action: func(c *cli.Context) error {
+		if err := bucket.RequireOnline("installing servers"); err != nil {
+			return cli.Exit(err, 1)
+		}
+
+		pltype := bucket.GetPlatform(c.String("platform"))
+		if pltype == nil {
+			return cli.Exit("unknown platform: "+c.String("platform"), 1)
+		}
+
+		return Workspace.RunWithContext("init", func(oc *bucket.OpenContext, log *log.Logger) error {
+			if oc.Platform != nil {
+				return cli.Exit("server already initialized ("+oc.PlatformName()+")", 1)
+			}
+
+			if err := pltype.Install(oc, c.String("version")); err != nil {
+				return err
+			}
+
+			oc.Platform = pltype.Build(oc)
+			if err := oc.Fs.MkdirAll(oc.Platform.PluginsFolder(), 0755); err != nil {
+				return err
+			}
+
+			if c.Bool("accept-eula") {
+				if err := oc.Fs.WriteFile(EulaFile, []byte("eula=true\n"), 0644); err != nil {
+					return err
+				}
+			} else {
+				log.Println("the server won't start until the EULA is accepted in " + EulaFile)
+			}
+
+			// Only the local options, not the ones collapsed from the global config
+			conf, err := bucket.LoadFilesystemConfig(oc.Fs, bucket.ConfigName)
+			if err != nil {
+				return err
+			} else if conf == nil {
+				conf = &bucket.Config{}
+			}
+
+			conf.Platform = pltype.Name
+			if err := bucket.WriteFilesystemConfig(oc.Fs, bucket.ConfigName, conf); err != nil {
+				return err
+			}
+
+			log.Printf("initialized %s server\n", pltype.Name)
+
+			return nil
+		})
+	},
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/MRtecno98/bucket/bucket"
+	"github.com/urfave/cli/v2"
+)
+
+var LIST = &cli.Command{
+	Name:    "list",
+	Aliases: []string{"l", "ls"},
+	Usage:   "lists installed plugins and their resolution status",
+	Before:  InitializeContexts(true),
+	After:   ShutdownContexts,
+	Action: func(c *cli.Context) error {
+		return Workspace.RunWithContext("list", func(oc *bucket.OpenContext, log *log.Logger) error {
+			if oc.Platform == nil {
+				return cli.Exit("no platform detected", 1)
+			}
+
+			pls, perrs, err := oc.Platform.Plugins()
+			if err != nil && len(pls) == 0 {
+				return err
+			}
+
+			for _, e := range perrs {
+				log.Printf("warn: %v\n", e)
+			}
+
+			slices.SortFunc(pls, func(a, b bucket.Plugin) int {
+				return strings.Compare(strings.ToLower(a.GetName()), strings.ToLower(b.GetName()))
+			})
+
+			for _, pl := range pls {
+				status := ListStatus(oc, pl)
+				if !oc.Platform.Type().Supports(pl) {
+					status += " (unsupported on " + oc.PlatformName() + ")"
+				}
+
+				log.Printf("%s %s\n", pl.GetName(), status)
+			}
+
+			return nil
+		})
+	},
+}
+
+func ListStatus(oc *bucket.OpenContext, pl bucket.Plugin) string {
+	version := ""
+	if v, ok := pl.(bucket.Versionable); ok {
+		version = v.GetVersion() + " "
+	}
+
+	if rec, ok := oc.Plugins().GetFirst(pl.GetIdentifier()); ok {
+		return version + "[" + rec.Repository.GetName() + "] " + rec.RemoteIdentifier
+	}
+
+	if rec, ok := oc.GetUnresolved(pl.GetIdentifier()); ok {
+		if local, ok := pl.(*bucket.LocalPlugin); ok {
+			if hash, err := local.Hash(); err == nil &&
+				rec.Valid(hash, oc.Config().UnresolvedExpiration()) {
+				return version + "unresolved (cached)"
+			}
+		}
+	}
+
+	return version + "unresolved"
+}
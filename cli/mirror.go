@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/MRtecno98/afero"
+	"github.com/MRtecno98/bucket/bucket"
+	"github.com/urfave/cli/v2"
+)
+
+var MIRROR = &cli.Command{
+	Name:  "mirror",
+	Usage: "reproduces the plugins of a server on other servers",
+
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "from",
+			Usage:    "copies the plugins of `CONTEXT`, by name or URL",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "to",
+			Usage:    "comma separated `CONTEXTS` to update, by name or URL",
+			Required: true,
+		},
+		&cli.StringSliceFlag{
+			Name:  "include",
+			Usage: "only mirrors the plugins matching `PATTERN`",
+		},
+		&cli.StringSliceFlag{
+			Name:  "exclude",
+			Usage: "leaves the plugins matching `PATTERN` untouched",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "prints the plan without changing the targets",
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "mirrors on targets whose platform can't run the source plugins",
+		},
+	},
+
+	Before: func(c *cli.Context) error {
+		bucket.LoadSystemConfig(afero.NewOsFs(), c.String("config"))
+
+		// The source is the first context of the workspace
+		contexts := []bucket.Context{bucket.GlobalConfig.FindContext(c.String("from"))}
+		for _, name := range strings.Split(c.String("to"), ",") {
+			contexts = append(contexts, bucket.GlobalConfig.FindContext(name))
+		}
+
+		var err error
+		Workspace, err = bucket.CreateWorkspace(c.Context, contexts...)
+		if err != nil {
+			return err
+		}
+
+		for _, oc := range Workspace.Contexts {
+			if err := oc.LoadPluginDatabase(); err != nil {
+				return fmt.Errorf("failed to load database for %s: %v", oc.Name, err)
+			}
+		}
+
+		return nil
+	},
+	After: ShutdownContexts,
+	Action: func(c *cli.Context) error {
+		source := Workspace.Contexts[0]
+		if source.Platform == nil {
+			return cli.Exit("no platform detected in "+source.Name, 1)
+		}
+
+		filter := bucket.MirrorFilter{Include: c.StringSlice("include"), Exclude: c.StringSlice("exclude")}
+		targets := &bucket.Workspace{Contexts: Workspace.Contexts[1:]}
+
+		return targets.RunWithContext("mirror", func(oc *bucket.OpenContext, log *log.Logger) error {
+			if oc.Platform == nil {
+				return errors.New("no platform detected")
+			}
+
+			if !oc.Platform.Type().AnyCompatible(source.Platform.Type().EveryCompatible()) {
+				if !c.Bool("force") {
+					return fmt.Errorf("%s plugins don't run on %s, use --force to mirror anyway",
+						source.PlatformName(), oc.PlatformName())
+				}
+
+				log.Printf("warn: forcing %s plugins on %s\n", source.PlatformName(), oc.PlatformName())
+			} else if oc.PlatformName() != source.PlatformName() {
+				log.Printf("warn: mirroring %s plugins on %s\n", source.PlatformName(), oc.PlatformName())
+			}
+
+			plan, err := oc.PlanMirror(c.Context, source, filter)
+			if err != nil {
+				return err
+			}
+
+			for _, e := range plan.SourceErrors {
+				log.Printf("warn: %s: %v\n", source.Name, e)
+			}
+
+			if len(plan.SourceErrors) > 0 {
+				log.Printf("warn: some plugins of %s failed to load, not removing any plugin\n", source.Name)
+			}
+
+			if plan.Empty() {
+				log.Printf("already in sync with %s\n", source.Name)
+				return nil
+			}
+
+			for _, install := range plan.Install {
+				if install.Replaces != nil {
+					log.Printf("~ %s %s -> %s [%s]\n", install.Name,
+						install.Replaces.Version, install.Version, install.Repository)
+				} else {
+					log.Printf("+ %s %s [%s]\n", install.Name, install.Version, install.Repository)
+				}
+			}
+
+			for _, pl := range plan.Copy {
+				log.Printf("+ %s (unresolved, copying the jar)\n", pl.GetName())
+			}
+
+			for _, state := range plan.Remove {
+				log.Printf("- %s %s\n", state.Name, state.Version)
+			}
+
+			if c.Bool("dry-run") {
+				log.Println("dry run, nothing changed")
+				return nil
+			}
+
+			if err := oc.ApplyMirror(c.Context, plan); err != nil {
+				return err
+			}
+
+			log.Printf("mirrored %s\n", source.Name)
+			return nil
+		})
+	},
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/MRtecno98/bucket/bucket"
+	"github.com/urfave/cli/v2"
+)
+
+var PACK = &cli.Command{
+	Name:   "pack",
+	Usage:  "packs the server configs and a plugin lockfile in a portable archive",
+	Before: InitializeContexts(true),
+	After:  ShutdownContexts,
+
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "writes the archive to `FILE`, named after the context if unset",
+		},
+		&cli.BoolFlag{
+			Name:  "worlds",
+			Usage: "packs the worlds too",
+		},
+	},
+
+	Action: func(c *cli.Context) error {
+		return Workspace.RunWithContext("pack", func(oc *bucket.OpenContext, log *log.Logger) error {
+			output := c.String("output")
+			if output == "" {
+				output = packName(oc) + bucket.PackExtension
+			}
+
+			file, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+
+			lock, unlocked, err := oc.Pack(c.Context, file, c.Bool("worlds"))
+			if cerr := file.Close(); err == nil {
+				err = cerr
+			}
+
+			if err != nil {
+				os.Remove(output)
+				return err
+			}
+
+			for _, pl := range unlocked {
+				log.Printf("warn: %s is unresolved, packing its jar\n", pl.GetName())
+			}
+
+			log.Printf("packed %s with %d locked plugins\n", output, len(lock.Plugins))
+			return nil
+		})
+	},
+}
+
+var UNPACK = &cli.Command{
+	Name:      "unpack",
+	Usage:     "extracts a packed server and downloads its locked plugins",
+	Before:    InitializeContexts(false),
+	After:     ShutdownContexts,
+	Args:      true,
+	ArgsUsage: " archive",
+
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "install-server",
+			Usage: "downloads the server jar of the packed platform too",
+		},
+	},
+
+	Action: func(c *cli.Context) error {
+		if c.Args().Len() == 0 {
+			return cli.Exit("missing archive", 1)
+		}
+
+		if err := bucket.RequireOnline("downloading plugins"); err != nil {
+			return cli.Exit(err, 1)
+		}
+
+		file, err := os.Open(c.Args().Get(0))
+		if err != nil {
+			return err
+		}
+
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+
+		return Workspace.RunWithContext("unpack", func(oc *bucket.OpenContext, log *log.Logger) error {
+			installed := oc.Platform != nil
+
+			lock, err := oc.Unpack(oc.Lock, file, info.Size())
+			if lock == nil {
+				return err
+			}
+
+			if err != nil {
+				log.Printf("warn: %v\n", err)
+			}
+
+			if c.Bool("install-server") && !installed {
+				if err := oc.Platform.Type().Install(oc, lock.GameVersion); err != nil {
+					return err
+				}
+			}
+
+			log.Printf("unpacked %s server with %d plugins\n", lock.Platform, len(lock.Plugins))
+			return nil
+		})
+	},
+}
+
+// packName names the archive after the context, or its folder for the cli ones
+func packName(oc *bucket.OpenContext) string {
+	if !strings.HasPrefix(oc.Name, "<") {
+		return oc.Name
+	}
+
+	if filepath.IsLocal(oc.URL) {
+		if abs, err := filepath.Abs(oc.URL); err == nil {
+			return filepath.Base(abs)
+		}
+	}
+
+	if name := path.Base(strings.TrimRight(oc.URL, "/")); name != "." && name != "/" {
+		return name
+	}
+
+	return "server"
+}
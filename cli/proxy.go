@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/MRtecno98/afero"
+	"github.com/MRtecno98/bucket/bucket"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/exp/maps"
+)
+
+var PROXY = &cli.Command{
+	Name:  "proxy",
+	Usage: "runs a caching HTTP proxy for the plugin repositories",
+	Before: func(c *cli.Context) error {
+		bucket.LoadSystemConfig(afero.NewOsFs(), c.String("config"))
+		return nil
+	},
+
+	After: ShutdownContexts,
+
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "listen",
+			Aliases: []string{"l"},
+			Usage:   "listens on `ADDRESS`",
+			Value:   bucket.DefaultProxyAddress,
+		},
+	},
+
+	Action: func(c *cli.Context) error {
+		handler, err := bucket.NewProxyHandler(bucket.SharedTransport())
+		if err != nil {
+			return err
+		}
+
+		server := &http.Server{
+			Addr:              c.String("listen"),
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		routes := maps.Keys(bucket.ProxyUpstreams)
+		slices.Sort(routes)
+
+		log.Printf("proxy listening on %s\n", server.Addr)
+		for _, r := range routes {
+			fmt.Printf("\t%s -> %s\n", r, bucket.ProxyUpstreams[r])
+		}
+
+		go func() {
+			<-c.Context.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			server.Shutdown(ctx)
+		}()
+
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+
+		return nil
+	},
+}
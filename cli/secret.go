@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/MRtecno98/bucket/bucket"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+)
+
+var storeFlag = &cli.StringFlag{
+	Name:        "store",
+	Usage:       "uses the secret store `NAME` in ~/.bucket/secrets",
+	Value:       bucket.DefaultSecretStore,
+	DefaultText: bucket.DefaultSecretStore,
+}
+
+var SECRET = &cli.Command{
+	Name:  "secret",
+	Usage: "manages the encrypted secret store used by the config overlays",
+
+	Subcommands: []*cli.Command{
+		{
+			Name:      "set",
+			Usage:     "stores a secret, reading its value from the terminal or stdin",
+			Args:      true,
+			ArgsUsage: " name",
+			Flags:     []cli.Flag{storeFlag},
+
+			Action: func(c *cli.Context) error {
+				if c.Args().Len() == 0 {
+					return cli.Exit("missing secret name", 1)
+				}
+
+				store, err := bucket.OpenSecretStore(c.String("store"))
+				if err != nil {
+					return err
+				}
+
+				value, err := readSecret(c.Args().Get(0))
+				if err != nil {
+					return err
+				}
+
+				if value == "" {
+					return cli.Exit("empty secret", 1)
+				}
+
+				if err := store.Set(c.Args().Get(0), value); err != nil {
+					return err
+				}
+
+				fmt.Printf("stored %s in %s\n", c.Args().Get(0), store.Path)
+				return nil
+			},
+		},
+		{
+			Name:      "remove",
+			Aliases:   []string{"rm"},
+			Usage:     "deletes a secret",
+			Args:      true,
+			ArgsUsage: " name",
+			Flags:     []cli.Flag{storeFlag},
+
+			Action: func(c *cli.Context) error {
+				if c.Args().Len() == 0 {
+					return cli.Exit("missing secret name", 1)
+				}
+
+				store, err := bucket.OpenSecretStore(c.String("store"))
+				if err != nil {
+					return err
+				}
+
+				if err := store.Set(c.Args().Get(0), ""); err != nil {
+					return err
+				}
+
+				fmt.Printf("deleted %s\n", c.Args().Get(0))
+				return nil
+			},
+		},
+		{
+			Name:    "list",
+			Aliases: []string{"ls"},
+			Usage:   "lists the secret names, never their values",
+			Flags:   []cli.Flag{storeFlag},
+
+			Action: func(c *cli.Context) error {
+				store, err := bucket.OpenSecretStore(c.String("store"))
+				if err != nil {
+					return err
+				}
+
+				names, err := store.Names()
+				if err != nil {
+					return err
+				}
+
+				for _, name := range names {
+					fmt.Println(name)
+				}
+
+				return nil
+			},
+		},
+	},
+}
+
+func readSecret(name string) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Fprintf(os.Stderr, "value of %s: ", name)
+	value, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+
+	return string(value), err
+}
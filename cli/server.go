@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/MRtecno98/bucket/bucket"
+	"github.com/urfave/cli/v2"
+)
+
+var SERVER = &cli.Command{
+	Name:  "server",
+	Usage: "manages the server installation",
+
+	Subcommands: []*cli.Command{
+		CONFIGURE, UPGRADE,
+	},
+}
+
+var CONFIGURE = &cli.Command{
+	Name:   "configure",
+	Usage:  "applies the server section of the config to the server config files",
+	Before: InitializeContexts(false),
+	After:  ShutdownContexts,
+
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "check",
+			Usage: "only reports the settings that differ, without writing",
+		},
+	},
+
+	Action: func(c *cli.Context) error {
+		return Workspace.RunWithContext("configure", func(oc *bucket.OpenContext, log *log.Logger) error {
+			changes, err := oc.ConfigureServer(c.Bool("check"))
+			if err != nil {
+				return err
+			}
+
+			for _, ch := range changes {
+				old := ch.Old
+				if ch.Missing {
+					old = "<unset>"
+				}
+
+				log.Printf("%s: %s %s -> %s\n", ch.File, ch.Key(), old, ch.New)
+			}
+
+			if c.Bool("check") && len(changes) > 0 {
+				return cli.Exit(fmt.Sprintf("%d settings drifted", len(changes)), 1)
+			}
+
+			if c.Bool("check") {
+				log.Println("server configured as declared")
+			} else {
+				log.Printf("changed %d settings\n", len(changes))
+			}
+
+			return nil
+		})
+	},
+}
+
+var UPGRADE = &cli.Command{
+	Name:   "upgrade",
+	Usage:  "updates the server jar or switches it to another platform",
+	Before: InitializeContexts(true),
+	After:  ShutdownContexts,
+
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "platform",
+			Usage: "switches the server to `PLATFORM`",
+		},
+		&cli.StringFlag{
+			Name:  "version",
+			Usage: "upgrades the server to `VERSION`, the current one if unset",
+		},
+		&cli.BoolFlag{
+			Name:  "force",
+			Usage: "switches even if some plugins aren't compatible",
+		},
+		&cli.BoolFlag{
+			Name:  "rollback",
+			Usage: "restores the server jar replaced by the last upgrade",
+		},
+	},
+
+	Action: func(c *cli.Context) error {
+		return Workspace.RunWithContext("upgrade", func(oc *bucket.OpenContext, log *log.Logger) error {
+			if c.Bool("rollback") {
+				if err := oc.RollbackServer(); err != nil {
+					return err
+				}
+
+				log.Println("restored the previous server jar")
+				return nil
+			}
+
+			if oc.Platform == nil {
+				return cli.Exit("no platform detected", 1)
+			}
+
+			if err := bucket.RequireOnline("upgrading servers"); err != nil {
+				return cli.Exit(err, 1)
+			}
+
+			current := oc.Platform.Type()
+			target := current
+			if name := c.String("platform"); name != "" {
+				plt := bucket.GetPlatform(name)
+				if plt == nil {
+					return cli.Exit("unknown platform: "+name, 1)
+				}
+
+				target = *plt
+			}
+
+			version := c.String("version")
+			if version == "" {
+				version = oc.GameVersion()
+			}
+
+			// The latest release would be installed without checking the plugins
+			if version == "" {
+				return cli.Exit("minecraft version not detected, use --version", 1)
+			}
+
+			// A newer build of the same server doesn't change the plugins
+			if target.Name != current.Name || version != oc.GameVersion() {
+				check, err := oc.CheckUpgrade(oc.Lock, target, version)
+				if err != nil {
+					return err
+				}
+
+				for _, u := range check.Updates {
+					log.Printf("update available: %s\n", u)
+				}
+
+				for _, i := range check.Issues {
+					log.Printf("incompatible: %s\n", i)
+				}
+
+				if len(check.Issues) > 0 && !c.Bool("force") {
+					return cli.Exit("upgrade the listed plugins first, or use --force", 1)
+				}
+			}
+
+			if err := oc.UpgradeServer(target, version); err != nil {
+				return err
+			}
+
+			log.Printf("upgraded to %s, the previous jar is kept in %s\n", target.Name, bucket.RollbackFolder)
+
+			return nil
+		})
+	},
+}
@@ -14,7 +14,7 @@ var AddPlugin = &bucket.Task{
 	Priority: 0,
 
 	Func: func(oc *bucket.OpenContext, log *log.Logger) error {
-		res, _, err := oc.Repositories["spigotmc"].Search(os.Args[2], 5)
+		res, _, err := oc.Repositories["spigotmc"].Search(oc.Lock, os.Args[2], 5)
 		if err != nil {
 			return err
 		}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"runtime/pprof"
+	"syscall"
 	"time"
 
 	"github.com/MRtecno98/bucket/bucket"
@@ -20,13 +23,27 @@ import (
 )
 
 var profile bool
+var timeout time.Duration
 
 func main() {
 	log.SetPrefix("bucket: ")
 	log.SetFlags(0)
+	log.SetOutput(bucket.NewMaskingWriter(os.Stderr))
 
 	defer pprof.StopCPUProfile()
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		// A second interrupt kills the process right away
+		<-ctx.Done()
+		stop()
+	}()
+
+	cancel := context.CancelFunc(func() {})
+	defer func() { cancel() }()
+
 	cli.VersionFlag = &cli.BoolFlag{
 		Name:    "version",
 		Aliases: []string{"V"},
@@ -82,6 +99,25 @@ func main() {
 				Destination: &bucket.GlobalConfig.SumDB,
 			},
 
+			&cli.BoolFlag{
+				Name:        "offline",
+				Usage:       "works from cached data only, without network access",
+				EnvVars:     []string{"bucket.offline"},
+				Destination: &bucket.Offline,
+			},
+
+			&cli.BoolFlag{
+				Name:        "retry-unresolved",
+				Usage:       "retries the resolution of plugins previously not found",
+				Destination: &bucket.RetryUnresolved,
+			},
+
+			&cli.DurationFlag{
+				Name:        "timeout",
+				Usage:       "aborts the command after `DURATION`",
+				Destination: &timeout,
+			},
+
 			&cli.BoolFlag{
 				Name:        "debug",
 				Aliases:     []string{"v"},
@@ -118,6 +154,10 @@ func main() {
 				}
 			}
 
+			if timeout > 0 {
+				ctx.Context, cancel = context.WithTimeout(ctx.Context, timeout)
+			}
+
 			if ctx.Bool("plain") {
 				os.Setenv("bucket.plain", "true")
 			}
@@ -133,5 +173,12 @@ func main() {
 		},
 
 		Commands: c.Commands,
-	}).Run(os.Args)
+	}).RunContext(ctx, os.Args)
+
+	// Failed commands and checks must be seen by scripts and CI
+	if code := c.ExitCode(); code != 0 {
+		pprof.StopCPUProfile()
+		cancel()
+		os.Exit(code)
+	}
 }